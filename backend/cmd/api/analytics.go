@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"stockcast/internal/analytics"
+	"strings"
+	"time"
+)
+
+// maxCompareCodes bounds how many codes a single comparison or correlation request can load
+const maxCompareCodes = 20
+
+// readCodes reads a list of trading codes, uppercased and without duplicates
+func (app *application) readCodes(r *http.Request, key string) ([]string, error) {
+	raw := app.readCSV(r.URL.Query(), key, nil)
+
+	seen := make(map[string]bool, len(raw))
+	codes := make([]string, 0, len(raw))
+	for _, code := range raw {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+
+	if len(codes) < 2 || len(codes) > maxCompareCodes {
+		return nil, fmt.Errorf("must contain between 2 and %d trading codes", maxCompareCodes)
+	}
+	return codes, nil
+}
+
+func (app *application) getComparison(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Codes []string
+		Start string
+		End   string
+		Base  *float64
+	}
+
+	qs := r.URL.Query()
+	errs := map[string]string{}
+
+	var err error
+	if input.Codes, err = app.readCodes(r, "codes"); err != nil {
+		errs["codes"] = err.Error()
+	}
+	if input.Base, err = app.readOptionalFloat(qs, "base"); err != nil {
+		errs["base"] = err.Error()
+	}
+	if input.Base == nil {
+		base := 100.0
+		input.Base = &base
+	}
+	if *input.Base <= 0 {
+		errs["base"] = "base must be greater than zero"
+	}
+	if len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	input.Start = app.readString(qs, "start", "")
+	input.End = app.readString(qs, "end", "")
+	start := app.parseDate(input.Start, time.Now().AddDate(-1, 0, 0))
+	end := app.parseDate(input.End, time.Now())
+
+	ctx := r.Context()
+	stocks, err := app.store.Stocks.GetByCodes(ctx, input.Codes, start, end)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{"comparison": analytics.Compare(stocks, input.Codes, *input.Base)}
+	if err := app.writeJSON(w, http.StatusOK, data, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) getCorrelation(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Codes  []string
+		Start  string
+		End    string
+		Window int
+	}
+
+	qs := r.URL.Query()
+	errs := map[string]string{}
+
+	var err error
+	if input.Codes, err = app.readCodes(r, "codes"); err != nil {
+		errs["codes"] = err.Error()
+	}
+	input.Window = app.readInt(qs, "window", 0)
+	if input.Window != 0 {
+		if input.Window < 5 || input.Window > 250 {
+			errs["window"] = "window must be between 5 and 250 trading days"
+		}
+		if len(input.Codes) != 2 {
+			errs["codes"] = "a rolling correlation needs exactly 2 trading codes"
+		}
+	}
+	if len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	input.Start = app.readString(qs, "start", "")
+	input.End = app.readString(qs, "end", "")
+	start := app.parseDate(input.Start, time.Now().AddDate(-1, 0, 0))
+	end := app.parseDate(input.End, time.Now())
+
+	ctx := r.Context()
+	stocks, err := app.store.Stocks.GetByCodes(ctx, input.Codes, start, end)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{"correlation": analytics.Correlate(stocks, input.Codes)}
+	if input.Window != 0 {
+		data["rolling"] = analytics.Rolling(stocks, input.Codes[0], input.Codes[1], input.Window)
+	}
+	if err := app.writeJSON(w, http.StatusOK, data, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
@@ -1,22 +1,40 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"stockcast/internal/cache"
+	"stockcast/internal/predictor"
 	"stockcast/internal/store"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 )
 
 type application struct {
-	cfg    Config
-	logger *zap.SugaredLogger
-	store  store.Storage
-	wg     sync.WaitGroup
+	cfg       Config
+	logger    *zap.SugaredLogger
+	store     store.Storage
+	predictor *predictor.Client
+	// predictionCache holds recent forecasts, see predictionKey
+	predictionCache *cache.LRU[predictionKey, predictionResponse]
+	// predictionFlights shares one predictor call between concurrent misses for the same key
+	predictionFlights singleflight.Group
+	// jobWake tells an idle prediction job worker that a job was queued
+	jobWake chan struct{}
+	wg      sync.WaitGroup
+	// stopBackground cancels the context of background loops and running prediction jobs on shutdown
+	stopBackground context.CancelFunc
 }
 
 type Config struct {
@@ -26,6 +44,7 @@ type Config struct {
 	env         string
 	db          DbConfig
 	frontendURL string
+	predictor   predictorConfig
 }
 type authConfig struct {
 	basic basicConfig
@@ -41,6 +60,25 @@ type basicConfig struct {
 	user string
 	pass string
 }
+type predictorConfig struct {
+	url         string
+	timeout     time.Duration
+	callTimeout time.Duration
+	maxRetries  int
+	// the breaker opens after failureThreshold consecutive failures and stays open for cooldown
+	failureThreshold int
+	cooldown         time.Duration
+	// modelVersion is recorded with every stored prediction unless the predictor reports its own
+	modelVersion string
+	// workers bounds concurrent predictor calls of a batch, jobs is the number of job workers per process
+	workers int
+	jobs    int
+	// cacheSize bounds the in-memory prediction cache, cacheDB also reuses forecasts stored by earlier runs
+	cacheSize int
+	cacheDB   bool
+	// scoreInterval is how often realized prices are matched against stored predictions
+	scoreInterval time.Duration
+}
 type DbConfig struct {
 	addr        string
 	maxConnOpen int
@@ -56,7 +94,7 @@ func (app *application) mount() http.Handler {
 		// AllowOriginFunc:  func(r *http.Request, origin string) bool { return true },
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", "X-Cache"},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
@@ -72,9 +110,35 @@ func (app *application) mount() http.Handler {
 			r.Get("/", app.getStocks)
 			r.Get("/{tradingCodeID}", app.getStockByID)
 			r.Get("/{tradingCodeID}/history", app.getHistoryOfStockByID)
+			r.Get("/{tradingCodeID}/indicators", app.getIndicatorsOfStockByID)
+		})
+		r.Route("/companies", func(r chi.Router) {
+			r.Get("/", app.getCompanies)
+			r.Get("/{tradingCode}", app.getCompanyByCode)
+		})
+		r.Route("/market", func(r chi.Router) {
+			r.Get("/movers", app.getMarketMovers)
+			r.Get("/summary", app.getMarketSummary)
+			r.Get("/summary/history", app.getMarketSummaryHistory)
+		})
+		r.Get("/calendar", app.getCalendar)
+		r.Get("/compare", app.getComparison)
+		r.Route("/analytics", func(r chi.Router) {
+			r.Get("/correlation", app.getCorrelation)
+		})
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(app.basicAuthMiddleware)
+			r.Get("/data-quality", app.getDataQuality)
 		})
 		r.Route("/predict", func(r chi.Router) {
 			r.Post("/", app.getPredictions)
+			r.Post("/batch", app.getBatchPredictions)
+			r.Post("/jobs", app.createPredictionJob)
+			r.Get("/jobs/{jobID}", app.getPredictionJob)
+		})
+		r.Route("/predictions", func(r chi.Router) {
+			r.Get("/", app.listPredictions)
+			r.Get("/accuracy", app.getPredictionAccuracy)
 		})
 	})
 
@@ -86,12 +150,48 @@ func (app *application) run(mux http.Handler) error {
 		Addr:    app.cfg.addr,
 		Handler: mux,
 	}
-	app.logger.Infow("server has started", "addr", app.cfg.addr, "env", app.cfg.env)
-	if err := server.ListenAndServe(); err != nil {
-		switch err {
-		case http.ErrServerClosed:
-			return err
+
+	shutdown := make(chan error)
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+		s := <-quit
+
+		app.logger.Infow("shutting down server", "signal", s.String())
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		if err := server.Shutdown(ctx); err != nil {
+			shutdown <- err
+			return
+		}
+
+		// cancel the background loops, running prediction jobs go back to the queue, then wait for
+		// the remaining background work within the same deadline
+		app.logger.Infow("completing background tasks", "addr", app.cfg.addr)
+		if app.stopBackground != nil {
+			app.stopBackground()
+		}
+
+		done := make(chan struct{})
+		go func() {
+			app.wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+			shutdown <- nil
+		case <-ctx.Done():
+			shutdown <- fmt.Errorf("background tasks did not finish before the shutdown deadline: %w", ctx.Err())
 		}
+	}()
+
+	app.logger.Infow("server has started", "addr", app.cfg.addr, "env", app.cfg.env)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	if err := <-shutdown; err != nil {
+		return err
 	}
 	app.logger.Infow("server has stopped", "addr", app.cfg.addr)
 	return nil
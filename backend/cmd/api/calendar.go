@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"stockcast/internal/calendar"
+	"time"
+)
+
+// tradingCalendar loads the DSE trading calendar with its holidays
+func (app *application) tradingCalendar(ctx context.Context) (*calendar.Calendar, error) {
+	days, err := app.store.Calendar.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return calendar.New(days), nil
+}
+
+func (app *application) getCalendar(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Year int `validate:"gte=2000,lte=2100"`
+	}
+
+	qs := r.URL.Query()
+	input.Year = app.readInt(qs, "year", time.Now().Year())
+
+	if err := validate.Struct(input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	cal, err := app.tradingCalendar(ctx)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	days := cal.Year(input.Year)
+	tradingDays := 0
+	for _, day := range days {
+		if day.IsTradingDay {
+			tradingDays++
+		}
+	}
+
+	data := envelope{"calendar": envelope{
+		"year":        input.Year,
+		"tradingDays": tradingDays,
+		"days":        days,
+	}}
+	if err := app.writeJSON(w, http.StatusOK, data, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
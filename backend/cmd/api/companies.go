@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"stockcast/internal/store"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (app *application) getCompanies(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	sector := app.readString(qs, "sector", "")
+	category := strings.ToUpper(app.readString(qs, "category", ""))
+
+	ctx := r.Context()
+	companies, err := app.store.Companies.Get(ctx, sector, category)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{"companies": companies}
+	if err := app.writeJSON(w, http.StatusOK, data, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) getCompanyByCode(w http.ResponseWriter, r *http.Request) {
+	tradingCode := chi.URLParam(r, "tradingCode")
+
+	ctx := r.Context()
+	company, err := app.store.Companies.GetByCode(ctx, tradingCode)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	data := envelope{"company": company}
+	if err := app.writeJSON(w, http.StatusOK, data, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
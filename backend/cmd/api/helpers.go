@@ -140,6 +140,68 @@ func (app *application) readInt(qs url.Values, key string, defaultValue int) int
 	return i
 }
 
+// reads an optional float value from the query key in the URL parameter.
+// returns nil when the key is missing
+func (app *application) readOptionalFloat(qs url.Values, key string) (*float64, error) {
+	s := qs.Get(key)
+
+	if s == "" {
+		return nil, nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a number", key)
+	}
+	return &f, nil
+}
+
+// reads an optional int value from the query key in the URL parameter.
+// returns nil when the key is missing
+func (app *application) readOptionalInt(qs url.Values, key string) (*int, error) {
+	s := qs.Get(key)
+
+	if s == "" {
+		return nil, nil
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an integer value", key)
+	}
+	return &i, nil
+}
+
+// projects every item onto the given JSON fields, unknown fields are reported as an error.
+// returns the items unchanged when no fields are requested
+func (app *application) selectFields(items any, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return items, nil
+	}
+
+	js, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	var rows []map[string]any
+	if err := json.Unmarshal(js, &rows); err != nil {
+		return nil, err
+	}
+
+	projected := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		projected[i] = make(map[string]any, len(fields))
+		for _, field := range fields {
+			value, ok := row[field]
+			if !ok {
+				return nil, fmt.Errorf("unknown field %q", field)
+			}
+			projected[i][field] = value
+		}
+	}
+	return projected, nil
+}
+
 func (app *application) parseDate(dateStr string, defaultDate time.Time) time.Time {
 	const layout = "2006-01-02"
 	if dateStr == "" {
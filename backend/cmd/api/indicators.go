@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"stockcast/internal/indicators"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type indicatorsResponse struct {
+	TradingCode string                       `json:"tradingCode"`
+	Dates       []time.Time                  `json:"dates"`
+	Close       []float64                    `json:"close"`
+	Series      map[string]indicators.Result `json:"series"`
+}
+
+func (app *application) getIndicatorsOfStockByID(w http.ResponseWriter, r *http.Request) {
+	tradingCodeID := chi.URLParam(r, "tradingCodeID")
+	var input struct {
+		Names []string
+		Start string
+		End   string
+	}
+
+	qs := r.URL.Query()
+	input.Names = app.readCSV(qs, "names", []string{"sma20", "ema50", "rsi14", "macd", "bbands"})
+	input.Start = app.readString(qs, "start", "")
+	input.End = app.readString(qs, "end", "")
+
+	errs := map[string]string{}
+	specs := make([]indicators.Spec, 0, len(input.Names))
+	warmup := 0
+	for _, name := range input.Names {
+		spec, err := indicators.Parse(name)
+		if err != nil {
+			errs[name] = err.Error()
+			continue
+		}
+		specs = append(specs, spec)
+		warmup = max(warmup, spec.Warmup())
+	}
+	if len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	start := app.parseDate(input.Start, time.Now().AddDate(0, -2, 0))
+	end := app.parseDate(input.End, time.Now())
+
+	ctx := r.Context()
+	stocks, err := app.store.Stocks.GetByIDWithWarmup(ctx, tradingCodeID, start, end, warmup)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// rows before start only feed the warm-up and are not part of the response
+	offset := len(stocks)
+	for i, stock := range stocks {
+		if !stock.Date.Before(start) {
+			offset = i
+			break
+		}
+	}
+	if offset == len(stocks) {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	closes := make([]float64, len(stocks))
+	for i, stock := range stocks {
+		closes[i] = stock.Closep
+	}
+
+	resp := indicatorsResponse{
+		TradingCode: tradingCodeID,
+		Dates:       make([]time.Time, 0, len(stocks)-offset),
+		Close:       closes[offset:],
+		Series:      make(map[string]indicators.Result, len(specs)),
+	}
+	for _, stock := range stocks[offset:] {
+		resp.Dates = append(resp.Dates, stock.Date)
+	}
+	for _, spec := range specs {
+		resp.Series[spec.Name] = spec.Compute(closes).Trim(offset)
+	}
+
+	data := envelope{"indicators": resp}
+	if err := app.writeJSON(w, http.StatusOK, data, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"time"
 
+	"stockcast/internal/cache"
 	"stockcast/internal/db"
 	"stockcast/internal/env"
+	"stockcast/internal/predictor"
 	"stockcast/internal/store"
 
 	"github.com/joho/godotenv"
@@ -62,6 +65,21 @@ func main() {
 		apiUrl:      env.GetString("API_URL", "localhost:8080"),
 		frontendURL: env.GetString("FRONT_END_URL_PROD", "http://localhost:5173"),
 		auth:        authConfig,
+		predictor: predictorConfig{
+			url:     env.GetString("PREDICTOR_URL", "http://localhost:8000"),
+			timeout: time.Duration(env.GetInt("PREDICTOR_TIMEOUT_SECONDS", 20)) * time.Second,
+			// stays below the router's 60 second request timeout
+			callTimeout:      time.Duration(env.GetInt("PREDICTOR_CALL_TIMEOUT_SECONDS", 40)) * time.Second,
+			maxRetries:       env.GetInt("PREDICTOR_MAX_RETRIES", 2),
+			failureThreshold: env.GetInt("PREDICTOR_FAILURE_THRESHOLD", 5),
+			cooldown:         time.Duration(env.GetInt("PREDICTOR_COOLDOWN_SECONDS", 30)) * time.Second,
+			modelVersion:     env.GetString("PREDICTOR_MODEL_VERSION", "unified-lstm"),
+			workers:          env.GetInt("PREDICTOR_WORKERS", 4),
+			jobs:             env.GetInt("PREDICTION_JOBS", 1),
+			cacheSize:        env.GetInt("PREDICTION_CACHE_SIZE", 2000),
+			cacheDB:          env.GetBool("PREDICTION_CACHE_DB", true),
+			scoreInterval:    time.Hour,
+		},
 	}
 
 	db, err := db.New(config.db.addr, config.db.maxConnOpen, config.db.maxIdleConn, config.db.maxIdleTime)
@@ -76,7 +94,32 @@ func main() {
 		cfg:    config,
 		logger: logger,
 		store:  store,
+		predictor: predictor.New(predictor.Config{
+			BaseURL:          config.predictor.url,
+			Timeout:          config.predictor.timeout,
+			CallTimeout:      config.predictor.callTimeout,
+			MaxRetries:       config.predictor.maxRetries,
+			Backoff:          200 * time.Millisecond,
+			FailureThreshold: config.predictor.failureThreshold,
+			Cooldown:         config.predictor.cooldown,
+		}),
+		predictionCache: cache.NewLRU[predictionKey, predictionResponse](config.predictor.cacheSize),
+		jobWake:         make(chan struct{}, 1),
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	app.stopBackground = stop
+	app.background(func() {
+		app.scorePredictions(ctx, app.cfg.predictor.scoreInterval)
+	})
+	// workers also resume jobs left queued by a shutdown or abandoned by a crashed process
+	for range max(config.predictor.jobs, 1) {
+		app.background(func() {
+			app.predictionJobWorker(ctx)
+		})
 	}
+
 	mux := app.mount()
-	logger.Fatal(app.run(mux))
+	if err := app.run(mux); err != nil {
+		logger.Fatal(err)
+	}
 }
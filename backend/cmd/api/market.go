@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"stockcast/internal/store"
+	"time"
+)
+
+func (app *application) getMarketMovers(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		By    string `validate:"oneof=gain loss value volume trade"`
+		Limit int    `validate:"gte=1,lte=100"`
+		Date  string
+	}
+
+	qs := r.URL.Query()
+	input.By = app.readString(qs, "by", "gain")
+	input.Limit = app.readInt(qs, "limit", 20)
+	input.Date = app.readString(qs, "date", "")
+
+	if err := validate.Struct(input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// a missing date means the latest trading day
+	var date *time.Time
+	if parsed := app.parseDate(input.Date, time.Time{}); !parsed.IsZero() {
+		date = &parsed
+	}
+
+	ctx := r.Context()
+	movers, err := app.store.Market.Movers(ctx, date, input.By, input.Limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{"movers": movers}
+	if err := app.writeJSON(w, http.StatusOK, data, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) getMarketSummary(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	// a missing date means the latest trading day
+	var date *time.Time
+	if parsed := app.parseDate(app.readString(qs, "date", ""), time.Time{}); !parsed.IsZero() {
+		date = &parsed
+	}
+
+	ctx := r.Context()
+	summary, err := app.store.Market.GetSummary(ctx, date)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	data := envelope{"summary": summary}
+	if err := app.writeJSON(w, http.StatusOK, data, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) getMarketSummaryHistory(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Start string
+		End   string
+	}
+
+	qs := r.URL.Query()
+	input.Start = app.readString(qs, "start", "")
+	input.End = app.readString(qs, "end", "")
+
+	start := app.parseDate(input.Start, time.Now().AddDate(0, -2, 0))
+	end := app.parseDate(input.End, time.Now())
+
+	ctx := r.Context()
+	summaries, err := app.store.Market.GetSummaryHistory(ctx, start, end)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{"summaries": summaries}
+	if err := app.writeJSON(w, http.StatusOK, data, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// basicAuthMiddleware guards admin routes with the AUTH_BASIC_USER and AUTH_BASIC_PASS credentials
+func (app *application) basicAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
+			app.authenticationRequiredResponse(w, r)
+			return
+		}
+
+		userMatch := subtle.ConstantTimeCompare([]byte(user), []byte(app.cfg.auth.basic.user)) == 1
+		passMatch := subtle.ConstantTimeCompare([]byte(pass), []byte(app.cfg.auth.basic.pass)) == 1
+		if !userMatch || !passMatch {
+			w.Header().Set("WWW-Authenticate", `Basic realm="restricted", charset="UTF-8"`)
+			app.invalidCredentialsResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
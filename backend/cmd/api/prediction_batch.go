@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"stockcast/internal/adjust"
+	"stockcast/internal/calendar"
+	"stockcast/internal/predictor"
+	"stockcast/internal/store"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the synchronous batch endpoint has to answer inside the router's 60 second timeout, so it takes a
+// limited number of code and horizon pairs and stops waiting on the predictor after syncBatchTimeout.
+// Larger batches go through POST /v1/predict/jobs, the morning report over all ~400 listed codes
+// has to use the job endpoint.
+const (
+	maxSyncBatchItems = 40
+	syncBatchTimeout  = 45 * time.Second
+)
+
+type batchPredictionRequest struct {
+	TradingCodes []string `json:"tradingCodes" validate:"required,min=1,max=500,dive,required,max=50"`
+	NAheads      []int    `json:"nheads" validate:"required,min=1,max=3,dive,oneof=1 3 7"`
+	Adjusted     bool     `json:"adjusted"`
+}
+
+// syncBatchPredictionRequest is batchPredictionRequest with the synchronous endpoint's limit on codes,
+// the limit on code and horizon pairs is checked in getBatchPredictions
+type syncBatchPredictionRequest struct {
+	TradingCodes []string `json:"tradingCodes" validate:"required,min=1,max=40,dive,required,max=50"`
+	NAheads      []int    `json:"nheads" validate:"required,min=1,max=3,dive,oneof=1 3 7"`
+	Adjusted     bool     `json:"adjusted"`
+}
+
+// normalize uppercases codes and drops duplicate codes and horizons
+func (b *batchPredictionRequest) normalize() {
+	seen := make(map[string]bool, len(b.TradingCodes))
+	codes := make([]string, 0, len(b.TradingCodes))
+	for _, code := range b.TradingCodes {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+	b.TradingCodes = codes
+
+	seenHorizon := make(map[int]bool, len(b.NAheads))
+	nheads := make([]int, 0, len(b.NAheads))
+	for _, nhead := range b.NAheads {
+		if seenHorizon[nhead] {
+			continue
+		}
+		seenHorizon[nhead] = true
+		nheads = append(nheads, nhead)
+	}
+	b.NAheads = nheads
+}
+
+// batchResult is the outcome for one trading code and horizon, exactly one of Prediction and Error is set
+type batchResult struct {
+	TradingCode string              `json:"tradingCode"`
+	NAhead      int                 `json:"nhead"`
+	Cached      bool                `json:"cached"`
+	Prediction  *predictionResponse `json:"prediction,omitempty"`
+	Error       string              `json:"error,omitempty"`
+}
+
+// predictBatch forecasts every code and horizon of input with at most cfg.predictor.workers concurrent
+// predictor calls. Failures are reported per result, the error is only set when the shared history or
+// calendar could not be loaded. progress, when not nil, is called after each result.
+func (app *application) predictBatch(ctx context.Context, input batchPredictionRequest, progress func(done, total int)) ([]*batchResult, error) {
+	cal, err := app.tradingCalendar(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	histories, err := app.store.Predictions.GetHistoryBatch(ctx, input.TradingCodes, cal.AddTradingDays(now, -historyLookbackDays), now)
+	if err != nil {
+		return nil, err
+	}
+
+	// adjust once per code, every horizon of a code shares the same history
+	if input.Adjusted {
+		actions, err := app.store.CorporateActions.GetByCodes(ctx, input.TradingCodes)
+		if err != nil {
+			return nil, err
+		}
+		for code, history := range histories {
+			histories[code] = adjust.Apply(history, actions[code])
+		}
+	}
+
+	results := make([]*batchResult, 0, len(input.TradingCodes)*len(input.NAheads))
+	for _, code := range input.TradingCodes {
+		for _, nhead := range input.NAheads {
+			results = append(results, &batchResult{TradingCode: code, NAhead: nhead})
+		}
+	}
+
+	var (
+		mu   sync.Mutex
+		done int
+		wg   sync.WaitGroup
+	)
+	jobs := make(chan *batchResult)
+	for range max(app.cfg.predictor.workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for result := range jobs {
+				app.predictBatchItem(ctx, cal, input.Adjusted, histories[result.TradingCode], result)
+
+				if progress != nil {
+					mu.Lock()
+					done++
+					progress(done, len(results))
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, result := range results {
+		jobs <- result
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, nil
+}
+
+// predictBatchItem fills in result, a panic is reported as an error of this item only since it runs
+// on a worker goroutine where it would otherwise take the whole process down
+func (app *application) predictBatchItem(ctx context.Context, cal *calendar.Calendar, adjusted bool, history []*store.Stock, result *batchResult) {
+	defer func() {
+		if p := recover(); p != nil {
+			app.logger.Errorw("batch prediction panicked", "tradingCode", result.TradingCode, "nhead", result.NAhead, "panic", p)
+			result.Prediction = nil
+			result.Error = "internal error"
+		}
+	}()
+
+	if len(history) < minHistoryDays {
+		result.Error = fmt.Sprintf("insufficient history: %d of %d trading days", len(history), minHistoryDays)
+		return
+	}
+
+	payload := predictionRequest{TradingCode: result.TradingCode, NAhead: result.NAhead, Adjusted: adjusted}
+	resp, cached, err := app.predict(ctx, cal, payload, history)
+	if err != nil {
+		result.Error = batchErrorMessage(err)
+		if result.Error == "" {
+			app.logger.Errorw("batch prediction failed", "tradingCode", result.TradingCode, "nhead", result.NAhead, "error", err)
+			result.Error = "internal error"
+		}
+		return
+	}
+	result.Prediction = &resp
+	result.Cached = cached
+}
+
+// batchErrorMessage describes errors a caller can act on and returns "" for internal ones
+func batchErrorMessage(err error) string {
+	var statusErr *predictor.StatusError
+	switch {
+	case errors.Is(err, predictor.ErrUnavailable):
+		return "the prediction service is temporarily unavailable"
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return "the batch ran out of time before this prediction"
+	case errors.As(err, &statusErr):
+		return statusErr.Body
+	}
+	return ""
+}
+
+// getBatchPredictions answers small batches inline, at most maxSyncBatchItems code and horizon pairs.
+// Anything larger, like the morning report, goes through POST /v1/predict/jobs.
+func (app *application) getBatchPredictions(w http.ResponseWriter, r *http.Request) {
+	var body syncBatchPredictionRequest
+	if err := app.readJSON(w, r, &body); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	input := batchPredictionRequest(body)
+	input.normalize()
+	if err := validate.Struct(syncBatchPredictionRequest(input)); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if items := len(input.TradingCodes) * len(input.NAheads); items > maxSyncBatchItems {
+		app.failedValidationResponse(w, r, map[string]string{
+			"tradingCodes": fmt.Sprintf("at most %d code and horizon pairs per request, got %d, use POST /v1/predict/jobs for larger batches", maxSyncBatchItems, items),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), syncBatchTimeout)
+	defer cancel()
+
+	results, err := app.predictBatch(ctx, input, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+
+	data := envelope{"batch": envelope{
+		"total":     len(results),
+		"succeeded": len(results) - failed,
+		"failed":    failed,
+		"results":   results,
+	}}
+	if err := app.writeJSON(w, http.StatusOK, data, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"stockcast/internal/calendar"
+	"stockcast/internal/store"
+	"time"
+)
+
+// predictionKey identifies a forecast by its inputs. dataVersion changes whenever anything the
+// forecast depends on changes, a new or corrected history row, a corporate action that changes the
+// adjusted history, a calendar edit that moves the forecast dates or a new model version, so stale
+// entries are simply never looked up again.
+type predictionKey struct {
+	tradingCode string
+	nhead       int
+	adjusted    bool
+	dataVersion string
+}
+
+// dataVersion hashes the history sent to the predictor, the trading days the forecast is aligned to
+// and the configured model version
+func dataVersion(history []*store.Stock, dates []time.Time, modelVersion string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", modelVersion)
+	for _, s := range history {
+		fmt.Fprintf(h, "%s|%g|%g|%g|%g|%g|%g|%d|%g|%d\n",
+			s.Date.Format("2006-01-02"), s.Ltp, s.High, s.Low, s.Openp, s.Closep, s.Ycp, s.Trade, s.Value, s.Volume)
+	}
+	for _, date := range dates {
+		fmt.Fprintf(h, "%s\n", date.Format("2006-01-02"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// String is the key the predictor calls are deduplicated on, see predict
+func (k predictionKey) String() string {
+	return fmt.Sprintf("%s|%d|%t|%s", k.tradingCode, k.nhead, k.adjusted, k.dataVersion)
+}
+
+// predictionFlight is what a deduplicated cache miss hands to every waiting request
+type predictionFlight struct {
+	resp predictionResponse
+	// stored is set when the forecast was found in the database instead of made by the predictor
+	stored bool
+}
+
+// predict returns the forecast for payload made from history, which must be non empty, and reports
+// whether it came from the cache. Concurrent misses for the same key share one predictor call.
+// Every request is recorded as a prediction row, cache hits with Cached set.
+func (app *application) predict(ctx context.Context, cal *calendar.Calendar, payload predictionRequest, history []*store.Stock) (predictionResponse, bool, error) {
+	last := history[len(history)-1]
+	dates := make([]time.Time, payload.NAhead)
+	for i := range dates {
+		dates[i] = cal.AddTradingDays(last.Date, i+1)
+	}
+	key := predictionKey{
+		tradingCode: payload.TradingCode,
+		nhead:       payload.NAhead,
+		adjusted:    payload.Adjusted,
+		dataVersion: dataVersion(history, dates, app.cfg.predictor.modelVersion),
+	}
+
+	if resp, ok := app.predictionCache.Get(key); ok {
+		if err := app.recordPrediction(ctx, resp, payload, last, key, true); err != nil {
+			return predictionResponse{}, false, err
+		}
+		return resp, true, nil
+	}
+
+	for {
+		// ran is only set in this request's own goroutine, Do runs fn synchronously for the leader
+		ran := false
+		v, err, _ := app.predictionFlights.Do(key.String(), func() (any, error) {
+			ran = true
+			return app.fetchPrediction(ctx, cal, payload, history, key)
+		})
+		if err != nil {
+			// the leader's request went away, this one is still waiting so it tries again
+			if !ran && ctx.Err() == nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+				continue
+			}
+			return predictionResponse{}, false, err
+		}
+
+		flight := v.(predictionFlight)
+		// the leader already recorded a fresh forecast, everything else is served from the cache
+		cached := flight.stored || !ran
+		if cached {
+			if err := app.recordPrediction(ctx, flight.resp, payload, last, key, true); err != nil {
+				return predictionResponse{}, false, err
+			}
+		}
+		return flight.resp, cached, nil
+	}
+}
+
+// fetchPrediction looks the forecast up in the database when cacheDB is set and otherwise asks the
+// predictor, recording the fresh forecast. Either way the result is added to the memory cache.
+func (app *application) fetchPrediction(ctx context.Context, cal *calendar.Calendar, payload predictionRequest, history []*store.Stock, key predictionKey) (predictionFlight, error) {
+	if app.cfg.predictor.cacheDB {
+		stored, err := app.store.Predictions.FindLatest(ctx, key.tradingCode, key.nhead, key.adjusted, key.dataVersion)
+		switch {
+		case err == nil && len(stored.Points) > 0:
+			resp := newPredictionResponse(stored)
+			app.predictionCache.Add(key, resp)
+			return predictionFlight{resp: resp, stored: true}, nil
+		case err != nil && !errors.Is(err, store.ErrorNotFound):
+			return predictionFlight{}, err
+		}
+	}
+
+	last := history[len(history)-1]
+	payload.History = history
+	var resp predictionResponse
+	if err := app.predictor.Predict(ctx, payload, &resp); err != nil {
+		return predictionFlight{}, err
+	}
+	resp.alignDates(cal, last.Date)
+	if resp.ModelVersion == "" {
+		resp.ModelVersion = app.cfg.predictor.modelVersion
+	}
+
+	if err := app.recordPrediction(ctx, resp, payload, last, key, false); err != nil {
+		return predictionFlight{}, err
+	}
+	app.predictionCache.Add(key, resp)
+	return predictionFlight{resp: resp}, nil
+}
+
+// recordPrediction stores resp as a prediction row for the audit trail
+func (app *application) recordPrediction(ctx context.Context, resp predictionResponse, payload predictionRequest, last *store.Stock, key predictionKey, cached bool) error {
+	prediction, err := resp.record(payload, last)
+	if err != nil {
+		return err
+	}
+	prediction.DataVersion = key.dataVersion
+	prediction.Cached = cached
+	return app.store.Predictions.Create(ctx, prediction)
+}
+
+// newPredictionResponse rebuilds the predictor reply from a stored prediction
+func newPredictionResponse(p *store.Prediction) predictionResponse {
+	day := PredictionDay{
+		PredictedPrices: make([]float64, len(p.Points)),
+		Dates:           make([]string, len(p.Points)),
+	}
+	for i, point := range p.Points {
+		day.PredictedPrices[i] = point.Price
+		day.Dates[i] = point.Date.Format("2006-01-02")
+	}
+	day.FinalPrice = day.PredictedPrices[len(day.PredictedPrices)-1]
+
+	return predictionResponse{
+		Success:         true,
+		TradingCode:     p.TradingCode,
+		Predictions:     map[string]PredictionDay{fmt.Sprintf("%d_day", p.Horizon): day},
+		DataPointsUsed:  p.DataPointsUsed,
+		PredictionDates: []string{day.Dates[len(day.Dates)-1]},
+		ModelVersion:    p.ModelVersion,
+	}
+}
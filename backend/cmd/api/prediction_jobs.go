@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"stockcast/internal/store"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// progress is written at most this often while a job runs, and always when it finishes
+	jobProgressInterval = time.Second
+	// a running job renews its lease every jobHeartbeatInterval, once its heartbeat is older than
+	// jobLease the runner is taken to be dead and the job is claimed again
+	jobHeartbeatInterval = 30 * time.Second
+	jobLease             = 2 * time.Minute
+	// a job is claimed at most this many times, after that it is marked failed
+	jobMaxAttempts = 3
+	// idle workers look for queued jobs this often, a new job also wakes one directly
+	jobPollInterval = 5 * time.Second
+)
+
+func (app *application) createPredictionJob(w http.ResponseWriter, r *http.Request) {
+	var input batchPredictionRequest
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	input.normalize()
+	if err := validate.Struct(input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	request, err := json.Marshal(input)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	job := &store.PredictionJob{
+		Status:  store.JobQueued,
+		Request: request,
+		Total:   len(input.TradingCodes) * len(input.NAheads),
+	}
+	if err := app.store.PredictionJobs.Create(r.Context(), job); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// wake an idle worker, the others pick the job up on their next poll
+	select {
+	case app.jobWake <- struct{}{}:
+	default:
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/predict/jobs/%d", job.ID))
+	if err := app.writeJSON(w, http.StatusAccepted, envelope{"job": job}, headers); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) getPredictionJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "jobID"), 10, 64)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	job, err := app.store.PredictionJobs.GetByID(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, envelope{"job": job}, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+// predictionJobWorker claims and runs queued jobs, and jobs whose runner died without a graceful
+// shutdown once their lease expires, until ctx is cancelled at shutdown
+func (app *application) predictionJobWorker(ctx context.Context) {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		job, err := app.store.PredictionJobs.Claim(ctx, jobLease, jobMaxAttempts)
+		switch {
+		case err == nil:
+			app.runPredictionJob(ctx, job)
+			continue
+		case errors.Is(err, store.ErrorNotFound):
+		case ctx.Err() == nil:
+			app.logger.Errorw("claiming prediction job failed", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-app.jobWake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// runPredictionJob executes a claimed job, renewing its lease while it runs. When ctx is cancelled by
+// a shutdown the job goes back to queued for the next process, and when the lease is lost to another
+// runner it stops without writing anything.
+func (app *application) runPredictionJob(ctx context.Context, job *store.PredictionJob) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// the job's own writes have to reach the database even after ctx is cancelled
+	saveCtx := context.WithoutCancel(ctx)
+
+	var mu sync.Mutex
+	lost := false
+	// save must be called with mu held
+	save := func() {
+		err := app.store.PredictionJobs.Update(saveCtx, job)
+		switch {
+		case errors.Is(err, store.ErrorNotFound):
+			app.logger.Warnw("prediction job was claimed by another runner", "job", job.ID)
+			lost = true
+			cancel()
+		case err != nil:
+			app.logger.Errorw("saving prediction job progress failed", "job", job.ID, "error", err)
+		}
+	}
+
+	// registered before the heartbeat is stopped below, so it runs after it
+	defer func() {
+		if p := recover(); p != nil {
+			app.finishPredictionJob(saveCtx, job, nil, fmt.Errorf("panic: %v", p))
+		}
+	}()
+
+	heartbeatDone := make(chan struct{})
+	stopHeartbeat := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		ticker := time.NewTicker(jobHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopHeartbeat:
+				return
+			case <-ticker.C:
+				mu.Lock()
+				save()
+				mu.Unlock()
+			}
+		}
+	}()
+	defer func() {
+		close(stopHeartbeat)
+		<-heartbeatDone
+	}()
+
+	var input batchPredictionRequest
+	if err := json.Unmarshal(job.Request, &input); err != nil {
+		mu.Lock()
+		defer mu.Unlock()
+		app.finishPredictionJob(saveCtx, job, nil, err)
+		return
+	}
+
+	lastSaved := time.Now()
+	results, err := app.predictBatch(ctx, input, func(done, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		job.Done, job.Total = done, total
+		if time.Since(lastSaved) < jobProgressInterval {
+			return
+		}
+		lastSaved = time.Now()
+		save()
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	switch {
+	case lost:
+		return
+	case ctx.Err() != nil:
+		app.logger.Infow("prediction job interrupted by shutdown, queued again", "job", job.ID)
+		if err := app.store.PredictionJobs.Requeue(saveCtx, job); err != nil {
+			app.logger.Errorw("requeueing prediction job failed", "job", job.ID, "error", err)
+		}
+	default:
+		app.finishPredictionJob(saveCtx, job, results, err)
+	}
+}
+
+func (app *application) finishPredictionJob(ctx context.Context, job *store.PredictionJob, results []*batchResult, err error) {
+	now := time.Now()
+	job.FinishedAt = &now
+
+	if err == nil {
+		failed := 0
+		for _, result := range results {
+			if result.Error != "" {
+				failed++
+			}
+		}
+		job.Result, err = json.Marshal(envelope{
+			"total":     len(results),
+			"succeeded": len(results) - failed,
+			"failed":    failed,
+			"results":   results,
+		})
+	}
+
+	if err != nil {
+		app.logger.Errorw("prediction job failed", "job", job.ID, "error", err)
+		job.Status = store.JobFailed
+		job.Error = "the job could not be completed"
+	} else {
+		job.Status = store.JobSucceeded
+		job.Done = job.Total
+	}
+
+	if err := app.store.PredictionJobs.Update(ctx, job); err != nil {
+		app.logger.Errorw("saving prediction job failed", "job", job.ID, "error", err)
+	}
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
-	"io"
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"sort"
+	"stockcast/internal/adjust"
+	"stockcast/internal/calendar"
+	"stockcast/internal/predictor"
 	"stockcast/internal/store"
 	"time"
 )
@@ -12,6 +16,7 @@ import (
 type predictionRequest struct {
 	TradingCode string         `json:"tradingCode" validate:"required,max=50"`
 	NAhead      int            `json:"nhead" validate:"required,oneof=1 3 7"`
+	Adjusted    bool           `json:"adjusted"`
 	History     []*store.Stock `json:"history"`
 }
 
@@ -27,6 +32,78 @@ type predictionResponse struct {
 	Predictions     map[string]PredictionDay `json:"predictions"`
 	DataPointsUsed  int                      `json:"data_points_used"`
 	PredictionDates []string                 `json:"prediction_dates"`
+	ModelVersion    string                   `json:"model_version,omitempty"`
+}
+
+// the predictor needs at least minHistoryDays rows, a few more trading days are fetched
+// so codes that missed some sessions still have enough history
+const (
+	minHistoryDays      = 60
+	historyLookbackDays = 80
+)
+
+// alignDates replaces the predictor's calendar day dates with real DSE sessions following the last history date
+func (p *predictionResponse) alignDates(cal *calendar.Calendar, lastDate time.Time) {
+	keys := make([]string, 0, len(p.Predictions))
+	for key := range p.Predictions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	p.PredictionDates = p.PredictionDates[:0]
+	for _, key := range keys {
+		day := p.Predictions[key]
+		dates := make([]string, len(day.PredictedPrices))
+		for i := range dates {
+			dates[i] = cal.AddTradingDays(lastDate, i+1).Format("2006-01-02")
+		}
+		day.Dates = dates
+		p.Predictions[key] = day
+		if len(dates) > 0 {
+			p.PredictionDates = append(p.PredictionDates, dates[len(dates)-1])
+		}
+	}
+}
+
+// predictorErrorResponse passes 4xx predictor replies such as an unknown trading code through to the
+// client and answers 503 while the predictor is down or keeps failing
+func (app *application) predictorErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	var statusErr *predictor.StatusError
+	switch {
+	case errors.Is(err, predictor.ErrUnavailable):
+		app.serviceUnavailableResponse(w, r, app.predictor.RetryAfter())
+	case errors.As(err, &statusErr):
+		app.errorResponse(w, r, statusErr.StatusCode, statusErr.Body)
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// record builds the stored form of the forecast for the requested horizon
+func (p *predictionResponse) record(payload predictionRequest, last *store.Stock) (*store.Prediction, error) {
+	prediction := &store.Prediction{
+		TradingCode:     payload.TradingCode,
+		Horizon:         payload.NAhead,
+		HistoryLastDate: last.Date,
+		LastClose:       last.Closep,
+		Adjusted:        payload.Adjusted,
+		ModelVersion:    p.ModelVersion,
+		DataPointsUsed:  p.DataPointsUsed,
+		Points:          []store.PredictionPoint{},
+	}
+
+	day := p.Predictions[fmt.Sprintf("%d_day", payload.NAhead)]
+	for i, price := range day.PredictedPrices {
+		if i >= len(day.Dates) {
+			break
+		}
+		date, err := time.Parse("2006-01-02", day.Dates[i])
+		if err != nil {
+			return nil, err
+		}
+		prediction.Points = append(prediction.Points, store.PredictionPoint{Date: date, Price: price})
+	}
+	return prediction, nil
 }
 
 func (app *application) getPredictions(w http.ResponseWriter, r *http.Request) {
@@ -41,8 +118,15 @@ func (app *application) getPredictions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	ctx := r.Context()
-	stockHistory, err := app.store.Predictions.GetHistory(ctx, payload.TradingCode, time.Now().AddDate(0, -4, 0), time.Now())
-	if len(stockHistory) < 60 {
+	cal, err := app.tradingCalendar(ctx)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	now := time.Now()
+	stockHistory, err := app.store.Predictions.GetHistory(ctx, payload.TradingCode, cal.AddTradingDays(now, -historyLookbackDays), now)
+	if len(stockHistory) < minHistoryDays {
 		app.notFoundResponse(w, r)
 		return
 	}
@@ -51,37 +135,125 @@ func (app *application) getPredictions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	payload.History = stockHistory
-	requestBody, err := json.Marshal(payload)
+	if payload.Adjusted {
+		actions, err := app.store.CorporateActions.GetByCode(ctx, payload.TradingCode)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		stockHistory = adjust.Apply(stockHistory, actions)
+	}
+
+	predictionResp, cached, err := app.predict(ctx, cal, payload, stockHistory)
 	if err != nil {
+		app.predictorErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("X-Cache", "MISS")
+	if cached {
+		headers.Set("X-Cache", "HIT")
+	}
+	if err := app.writeJSON(w, http.StatusOK, envelope{"prediction": predictionResp}, headers); err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
+}
 
-	resp, err := http.Post("http://localhost:8000/api/predict", "application/json", bytes.NewBuffer(requestBody))
+func (app *application) listPredictions(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code  string `validate:"max=50"`
+		Since string
+		Limit int `validate:"gte=1,lte=1000"`
+	}
+
+	qs := r.URL.Query()
+	input.Code = app.readString(qs, "code", "")
+	input.Since = app.readString(qs, "since", "")
+	input.Limit = app.readInt(qs, "limit", 100)
+
+	if err := validate.Struct(input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	since := app.parseDate(input.Since, time.Now().AddDate(0, 0, -30))
+
+	ctx := r.Context()
+	predictions, err := app.store.Predictions.List(ctx, input.Code, since, input.Limit)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			app.serverErrorResponse(w, r, err)
-			return
-		}
-		app.errorResponse(w, r, resp.StatusCode, string(body))
+
+	if err := app.writeJSON(w, http.StatusOK, envelope{"predictions": predictions}, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
+
+func (app *application) getPredictionAccuracy(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	filter := store.AccuracyFilter{
+		TradingCode: app.readString(qs, "code", ""),
+		Horizon:     app.readInt(qs, "horizon", 0),
+		Start:       app.parseDate(app.readString(qs, "start", ""), time.Now().AddDate(0, -3, 0)),
+		End:         app.parseDate(app.readString(qs, "end", ""), time.Now()),
+	}
+
+	if err := validate.Struct(filter); err != nil {
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	var predictionResp predictionResponse
-	if err := json.NewDecoder(resp.Body).Decode(&predictionResp); err != nil {
+	ctx := r.Context()
+	results, err := app.store.Predictions.Accuracy(ctx, filter)
+	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	if err := app.writeJSON(w, http.StatusOK, envelope{"prediction": predictionResp}, nil); err != nil {
+	overall := []*store.Accuracy{}
+	codes := []*store.Accuracy{}
+	for _, result := range results {
+		if result.TradingCode == "" {
+			overall = append(overall, result)
+		} else {
+			codes = append(codes, result)
+		}
+	}
+
+	data := envelope{"accuracy": envelope{
+		"start":   filter.Start,
+		"end":     filter.End,
+		"overall": overall,
+		"codes":   codes,
+	}}
+	if err := app.writeJSON(w, http.StatusOK, data, nil); err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 }
+
+// scorePredictions fills in realized prices for stored predictions every interval until ctx is done,
+// so accuracy catches up shortly after each import
+func (app *application) scorePredictions(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		scored, err := app.store.Predictions.Score(ctx)
+		if err != nil {
+			app.logger.Errorw("scoring predictions failed", "error", err)
+		} else if scored > 0 {
+			app.logger.Infow("scored predictions", "points", scored)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"stockcast/internal/quality"
+	"time"
+)
+
+func (app *application) getDataQuality(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Start         string
+		End           string
+		JumpThreshold float64 `validate:"gt=0,lte=10"`
+		StaleDays     int     `validate:"gte=1,lte=250"`
+	}
+
+	qs := r.URL.Query()
+	input.Start = app.readString(qs, "start", "")
+	input.End = app.readString(qs, "end", "")
+	input.StaleDays = app.readInt(qs, "stale_days", 10)
+
+	threshold, err := app.readOptionalFloat(qs, "threshold")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+	input.JumpThreshold = 0.25
+	if threshold != nil {
+		input.JumpThreshold = *threshold
+	}
+
+	if err := validate.Struct(input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	start := app.parseDate(input.Start, time.Now().AddDate(-1, 0, 0))
+	end := app.parseDate(input.End, time.Now())
+
+	ctx := r.Context()
+	cal, err := app.tradingCalendar(ctx)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	builder := quality.NewBuilder(cal, start, end, quality.Options{
+		JumpThreshold: input.JumpThreshold,
+		StaleDays:     input.StaleDays,
+	})
+	if err := app.store.Stocks.StreamRange(ctx, nil, start, end, builder.Add); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	data := envelope{"report": builder.Report()}
+	if err := app.writeJSON(w, http.StatusOK, data, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+}
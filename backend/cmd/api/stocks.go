@@ -2,20 +2,117 @@ package main
 
 import (
 	"net/http"
+	"stockcast/internal/adjust"
+	"stockcast/internal/candles"
+	"stockcast/internal/store"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 )
 
 func (app *application) getStocks(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		store.StockFilter
+		Fields []string
+	}
+
+	qs := r.URL.Query()
+	errs := map[string]string{}
+
+	input.Codes = app.readCSV(qs, "codes", nil)
+	for i, code := range input.Codes {
+		input.Codes[i] = strings.ToUpper(strings.TrimSpace(code))
+	}
+	input.Sort = app.readCSV(qs, "sort", []string{"tradingCode"})
+	// without page or page_size every matching row is returned, as before pagination existed
+	input.Page = app.readInt(qs, "page", 1)
+	paginate := qs.Has("page") || qs.Has("page_size")
+	if paginate {
+		input.PageSize = app.readInt(qs, "page_size", 500)
+	}
+	input.Fields = app.readCSV(qs, "fields", nil)
+
+	var err error
+	if input.MinLtp, err = app.readOptionalFloat(qs, "min_ltp"); err != nil {
+		errs["min_ltp"] = err.Error()
+	}
+	if input.MaxLtp, err = app.readOptionalFloat(qs, "max_ltp"); err != nil {
+		errs["max_ltp"] = err.Error()
+	}
+	if input.MinVolume, err = app.readOptionalInt(qs, "min_volume"); err != nil {
+		errs["min_volume"] = err.Error()
+	}
+	if input.MaxVolume, err = app.readOptionalInt(qs, "max_volume"); err != nil {
+		errs["max_volume"] = err.Error()
+	}
+	if paginate && input.PageSize < 1 {
+		errs["page_size"] = "must be between 1 and 1000"
+	}
+	if err := input.ValidateSort(); err != nil {
+		errs["sort"] = err.Error()
+	}
+	format, err := app.readFormat(r)
+	if err != nil {
+		errs["format"] = err.Error()
+	}
+
+	// start or end turns the request into an export of every row of the codes in the range,
+	// streamed without pagination, the snapshot filters and sort do not apply to it
+	ranged := qs.Has("start") || qs.Has("end")
+	start := app.parseDate(app.readString(qs, "start", ""), time.Now().AddDate(-1, 0, 0))
+	end := app.parseDate(app.readString(qs, "end", ""), time.Now())
+	if ranged {
+		switch {
+		case format == formatJSON:
+			errs["format"] = "start and end need format csv or ndjson, use /v1/stocks/{tradingCode}/history for JSON"
+		case end.Before(start):
+			errs["end"] = "must not be before start"
+		}
+	}
+	if len(errs) > 0 {
+		app.failedValidationResponse(w, r, errs)
+		return
+	}
+
+	if err := validate.Struct(input.StockFilter); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
 	ctx := r.Context()
-	stocks, err := app.store.Stocks.Get(ctx)
+	if format != formatJSON {
+		// streaming formats always use the make-csv column layout, fields only applies to JSON
+		sw := newStockWriter(w, format)
+		var err error
+		if ranged {
+			err = app.store.Stocks.StreamRange(ctx, input.Codes, start, end, sw.Write)
+		} else {
+			_, err = app.store.Stocks.Stream(ctx, input.StockFilter, sw.Write)
+		}
+		if err != nil {
+			app.streamError(w, r, sw, err)
+			return
+		}
+		if err := sw.Flush(); err != nil {
+			app.streamError(w, r, sw, err)
+		}
+		return
+	}
+
+	stocks, metadata, err := app.store.Stocks.Get(ctx, input.StockFilter)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	data := envelope{"stocks": stocks}
+	projected, err := app.selectFields(stocks, input.Fields)
+	if err != nil {
+		app.failedValidationResponse(w, r, map[string]string{"fields": err.Error()})
+		return
+	}
+
+	data := envelope{"stocks": projected, "metadata": metadata}
 	if err := app.writeJSON(w, http.StatusOK, data, nil); err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
@@ -26,24 +123,73 @@ func (app *application) getStocks(w http.ResponseWriter, r *http.Request) {
 func (app *application) getHistoryOfStockByID(w http.ResponseWriter, r *http.Request) {
 	tradingCodeID := chi.URLParam(r, "tradingCodeID")
 	var input struct {
-		Start string
-		End   string
+		Start    string
+		End      string
+		Interval string
+		Adjusted bool
 	}
 
 	qs := r.URL.Query()
 	input.Start = app.readString(qs, "start", "")
 	input.End = app.readString(qs, "end", "")
+	input.Interval = app.readString(qs, "interval", string(candles.Daily))
+	input.Adjusted = app.readString(qs, "adjusted", "false") == "true"
+
+	interval, err := candles.ParseInterval(input.Interval)
+	if err != nil {
+		app.failedValidationResponse(w, r, map[string]string{"interval": err.Error()})
+		return
+	}
+	format, err := app.readFormat(r)
+	if err != nil {
+		app.failedValidationResponse(w, r, map[string]string{"format": err.Error()})
+		return
+	}
 
 	start := app.parseDate(input.Start, time.Now().AddDate(0, -2, 0))
 	end := app.parseDate(input.End, time.Now())
 
+	// widen the range so the first candle covers its whole period
+	start = interval.PeriodStart(start)
+
 	ctx := r.Context()
+
+	// plain daily rows go straight from the database to the client
+	if format != formatJSON && interval == candles.Daily && !input.Adjusted {
+		sw := newStockWriter(w, format)
+		if err := app.store.Stocks.StreamByID(ctx, tradingCodeID, start, end, sw.Write); err != nil {
+			app.streamError(w, r, sw, err)
+			return
+		}
+		if err := sw.Flush(); err != nil {
+			app.streamError(w, r, sw, err)
+		}
+		return
+	}
+
 	stocks, err := app.store.Stocks.GetByID(ctx, tradingCodeID, start, end)
 	if err != nil {
 		app.notFoundResponse(w, r)
 		return
 	}
 
+	if input.Adjusted {
+		actions, err := app.store.CorporateActions.GetByCode(ctx, tradingCodeID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		stocks = adjust.Apply(stocks, actions)
+	}
+	stocks = candles.Resample(stocks, interval)
+
+	if format != formatJSON {
+		if err := app.writeStocks(w, format, stocks); err != nil {
+			app.logger.Errorw("stream aborted", "url", r.URL.String(), "error", err)
+		}
+		return
+	}
+
 	data := envelope{"stocks": stocks}
 	if err := app.writeJSON(w, http.StatusOK, data, nil); err != nil {
 		app.serverErrorResponse(w, r, err)
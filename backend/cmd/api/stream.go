@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"stockcast/internal/store"
+	"strconv"
+	"strings"
+)
+
+const (
+	formatJSON   = "json"
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+// flushEvery is how many rows are written between flushes to the client
+const flushEvery = 500
+
+// stockCSVHeader is the same column layout make-csv writes
+var stockCSVHeader = []string{"date", "trading_code", "ltp", "high", "low", "openp", "closep", "ycp", "trade", "value", "volume"}
+
+// reads the response format from the format query parameter, falling back to the Accept header.
+// returns json when neither asks for csv or ndjson
+func (app *application) readFormat(r *http.Request) (string, error) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "":
+	case formatJSON, formatCSV, formatNDJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("format must be one of %s, %s or %s", formatJSON, formatCSV, formatNDJSON)
+	}
+
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		switch mediaType {
+		case "text/csv":
+			return formatCSV, nil
+		case "application/x-ndjson":
+			return formatNDJSON, nil
+		}
+	}
+	return formatJSON, nil
+}
+
+// stockWriter streams stock rows to the client as CSV or NDJSON. Headers are only sent
+// with the first row, so a failure before that can still be answered with a JSON error.
+type stockWriter struct {
+	w       http.ResponseWriter
+	format  string
+	csv     *csv.Writer
+	json    *json.Encoder
+	started bool
+	rows    int
+}
+
+func newStockWriter(w http.ResponseWriter, format string) *stockWriter {
+	return &stockWriter{w: w, format: format}
+}
+
+func (sw *stockWriter) start() error {
+	sw.started = true
+	switch sw.format {
+	case formatCSV:
+		sw.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		sw.w.WriteHeader(http.StatusOK)
+		sw.csv = csv.NewWriter(sw.w)
+		return sw.csv.Write(stockCSVHeader)
+	default:
+		sw.w.Header().Set("Content-Type", "application/x-ndjson")
+		sw.w.WriteHeader(http.StatusOK)
+		sw.json = json.NewEncoder(sw.w)
+		return nil
+	}
+}
+
+// Write sends one row, flushing to the client every flushEvery rows
+func (sw *stockWriter) Write(stock *store.Stock) error {
+	if !sw.started {
+		if err := sw.start(); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if sw.csv != nil {
+		err = sw.csv.Write([]string{
+			stock.Date.Format("2006-01-02"),
+			stock.TradingCode,
+			formatDecimal(stock.Ltp),
+			formatDecimal(stock.High),
+			formatDecimal(stock.Low),
+			formatDecimal(stock.Openp),
+			formatDecimal(stock.Closep),
+			formatDecimal(stock.Ycp),
+			strconv.Itoa(stock.Trade),
+			formatDecimal(stock.Value),
+			strconv.Itoa(stock.Volume),
+		})
+	} else {
+		err = sw.json.Encode(stock)
+	}
+	if err != nil {
+		return err
+	}
+
+	sw.rows++
+	if sw.rows%flushEvery == 0 {
+		return sw.Flush()
+	}
+	return nil
+}
+
+// Flush pushes buffered rows to the client, sending the headers first when no row was written
+func (sw *stockWriter) Flush() error {
+	if !sw.started {
+		if err := sw.start(); err != nil {
+			return err
+		}
+	}
+	if sw.csv != nil {
+		sw.csv.Flush()
+		if err := sw.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if f, ok := sw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// writeStocks sends rows that are already in memory in the requested streaming format
+func (app *application) writeStocks(w http.ResponseWriter, format string, stocks []*store.Stock) error {
+	sw := newStockWriter(w, format)
+	for _, stock := range stocks {
+		if err := sw.Write(stock); err != nil {
+			return err
+		}
+	}
+	return sw.Flush()
+}
+
+// streamError answers with a JSON error if nothing was streamed yet, otherwise the response
+// is already under way and the error can only be logged
+func (app *application) streamError(w http.ResponseWriter, r *http.Request, sw *stockWriter, err error) {
+	if !sw.started {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	app.logger.Errorw("stream aborted", "url", r.URL.String(), "rows", sw.rows, "error", err)
+}
+
+// formatDecimal prints a float with as many decimals as needed and no trailing zeros
+func formatDecimal(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1 @@
+ALTER TABLE stock_history DROP CONSTRAINT IF EXISTS stock_history_trading_code_date_key;
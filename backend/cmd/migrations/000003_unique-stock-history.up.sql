@@ -0,0 +1,9 @@
+-- keep the most recently imported row of every (trading_code, date) pair
+DELETE FROM stock_history a
+USING stock_history b
+WHERE a.trading_code = b.trading_code
+  AND a.date = b.date
+  AND a.id < b.id;
+
+ALTER TABLE stock_history
+  ADD CONSTRAINT stock_history_trading_code_date_key UNIQUE (trading_code, date);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_instruments_sector;
+DROP TABLE IF EXISTS instruments;
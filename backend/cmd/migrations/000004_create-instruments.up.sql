@@ -0,0 +1,12 @@
+CREATE TABLE instruments (
+  trading_code VARCHAR(20) PRIMARY KEY,
+  company_name TEXT NOT NULL,
+  sector VARCHAR(100) NOT NULL DEFAULT '',
+  category VARCHAR(1) NOT NULL DEFAULT '' CHECK (category IN ('', 'A', 'B', 'N', 'Z')),
+  listing_date DATE,
+  face_value DOUBLE PRECISION,
+  total_shares BIGINT,
+  updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_instruments_sector ON instruments(sector);
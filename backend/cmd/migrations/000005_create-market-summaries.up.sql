@@ -0,0 +1,17 @@
+-- one row of market breadth and activity per trading day, refreshed by make-db after every import
+CREATE MATERIALIZED VIEW market_summaries AS
+SELECT
+  date,
+  COUNT(*) FILTER (WHERE ycp > 0 AND ltp > ycp) AS advancers,
+  COUNT(*) FILTER (WHERE ycp > 0 AND ltp < ycp) AS decliners,
+  COUNT(*) FILTER (WHERE ycp > 0 AND ltp = ycp) AS unchanged,
+  SUM(value) AS total_value,
+  SUM(volume) AS total_volume,
+  SUM(trade) AS total_trades,
+  COUNT(*) AS listed_codes,
+  COUNT(*) FILTER (WHERE trade > 0) AS traded_codes
+FROM stock_history
+GROUP BY date;
+
+-- required by REFRESH MATERIALIZED VIEW CONCURRENTLY
+CREATE UNIQUE INDEX idx_market_summaries_date ON market_summaries(date);
@@ -0,0 +1,12 @@
+-- ratio is the number of new shares per existing share: 0.1 for a 10% bonus, 2 for a 1:2 split,
+-- 0.5 for a 1R:2 rights issue priced at issue_price. cash_dividend is in Taka per share.
+CREATE TABLE corporate_actions (
+  id SERIAL PRIMARY KEY,
+  trading_code VARCHAR(20) NOT NULL,
+  record_date DATE NOT NULL,
+  action_type VARCHAR(10) NOT NULL CHECK (action_type IN ('bonus', 'rights', 'split', 'cash')),
+  ratio DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (ratio >= 0),
+  issue_price DOUBLE PRECISION,
+  cash_dividend DOUBLE PRECISION NOT NULL DEFAULT 0,
+  CONSTRAINT corporate_actions_code_date_type_key UNIQUE (trading_code, record_date, action_type)
+);
@@ -0,0 +1,9 @@
+-- completed date chunks of make-db backfill runs, so an interrupted run can resume
+CREATE TABLE ingest_checkpoints (
+  job VARCHAR(50) NOT NULL,
+  chunk_start DATE NOT NULL,
+  chunk_end DATE NOT NULL,
+  rows_received INTEGER NOT NULL,
+  completed_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (job, chunk_start, chunk_end)
+);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_ingest_rejects_reason;
+DROP INDEX IF EXISTS idx_ingest_rejects_created_at;
+DROP TABLE IF EXISTS ingest_rejects;
@@ -0,0 +1,14 @@
+-- rows make-db refused to import, kept with their original payload for inspection
+CREATE TABLE ingest_rejects (
+  id BIGSERIAL PRIMARY KEY,
+  source TEXT NOT NULL,
+  trading_code VARCHAR(50) NOT NULL DEFAULT '',
+  date VARCHAR(50) NOT NULL DEFAULT '',
+  reason VARCHAR(50) NOT NULL,
+  detail TEXT NOT NULL DEFAULT '',
+  payload JSONB NOT NULL,
+  created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_ingest_rejects_created_at ON ingest_rejects(created_at);
+CREATE INDEX idx_ingest_rejects_reason ON ingest_rejects(reason);
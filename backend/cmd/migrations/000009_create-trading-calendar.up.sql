@@ -0,0 +1,7 @@
+-- exceptions to the regular Sunday to Thursday DSE week: holidays on weekdays
+-- (is_trading_day = false) and special sessions on Fridays or Saturdays (is_trading_day = true)
+CREATE TABLE trading_calendar (
+  date DATE PRIMARY KEY,
+  is_trading_day BOOLEAN NOT NULL DEFAULT FALSE,
+  description TEXT NOT NULL DEFAULT ''
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS prediction_points;
+DROP TABLE IF EXISTS predictions;
@@ -0,0 +1,22 @@
+-- every forecast served by /v1/predict, prices are in adjusted terms when adjusted is true
+CREATE TABLE predictions (
+  id BIGSERIAL PRIMARY KEY,
+  trading_code VARCHAR(20) NOT NULL,
+  horizon INTEGER NOT NULL CHECK (horizon > 0),
+  requested_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  history_last_date DATE NOT NULL,
+  last_close DOUBLE PRECISION NOT NULL,
+  adjusted BOOLEAN NOT NULL DEFAULT FALSE,
+  model_version VARCHAR(50) NOT NULL DEFAULT '',
+  data_points_used INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE INDEX predictions_trading_code_requested_at_idx ON predictions (trading_code, requested_at DESC);
+CREATE INDEX predictions_requested_at_idx ON predictions (requested_at DESC);
+
+CREATE TABLE prediction_points (
+  prediction_id BIGINT NOT NULL REFERENCES predictions (id) ON DELETE CASCADE,
+  date DATE NOT NULL,
+  price DOUBLE PRECISION NOT NULL,
+  PRIMARY KEY (prediction_id, date)
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS prediction_points_unscored_idx;
+ALTER TABLE prediction_points
+  DROP COLUMN IF EXISTS scored_at,
+  DROP COLUMN IF EXISTS actual_price;
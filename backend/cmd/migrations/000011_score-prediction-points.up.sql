@@ -0,0 +1,6 @@
+-- actual_price is the realized closep for the point's date, filled in by the scoring job
+ALTER TABLE prediction_points
+  ADD COLUMN actual_price DOUBLE PRECISION,
+  ADD COLUMN scored_at TIMESTAMPTZ;
+
+CREATE INDEX prediction_points_unscored_idx ON prediction_points (date) WHERE scored_at IS NULL;
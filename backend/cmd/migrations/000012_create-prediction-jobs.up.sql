@@ -0,0 +1,15 @@
+-- asynchronous batch predictions, request holds the batch body and result the per code outcomes
+CREATE TABLE prediction_jobs (
+  id BIGSERIAL PRIMARY KEY,
+  status VARCHAR(10) NOT NULL DEFAULT 'queued' CHECK (status IN ('queued', 'running', 'succeeded', 'failed')),
+  request JSONB NOT NULL,
+  total INTEGER NOT NULL DEFAULT 0,
+  done INTEGER NOT NULL DEFAULT 0,
+  result JSONB,
+  error TEXT NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  started_at TIMESTAMPTZ,
+  finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX prediction_jobs_unfinished_idx ON prediction_jobs (id) WHERE status IN ('queued', 'running');
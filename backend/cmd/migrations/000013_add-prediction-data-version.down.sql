@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS predictions_cache_idx;
+ALTER TABLE predictions DROP COLUMN IF EXISTS data_version;
@@ -0,0 +1,5 @@
+-- data_version hashes every input of a prediction, the history rows sent to the predictor, the
+-- trading days it was aligned to and the model version, so stored forecasts are only reused for identical inputs
+ALTER TABLE predictions ADD COLUMN data_version VARCHAR(64) NOT NULL DEFAULT '';
+
+CREATE INDEX predictions_cache_idx ON predictions (trading_code, horizon, adjusted, data_version);
@@ -0,0 +1,3 @@
+ALTER TABLE prediction_jobs
+  DROP COLUMN IF EXISTS attempts,
+  DROP COLUMN IF EXISTS heartbeat_at;
@@ -0,0 +1,5 @@
+-- a running job holds a lease renewed through heartbeat_at, jobs whose lease ran out are claimed again.
+-- attempts counts claims, a runner may only update the job while its claim is the latest one.
+ALTER TABLE prediction_jobs
+  ADD COLUMN heartbeat_at TIMESTAMPTZ,
+  ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
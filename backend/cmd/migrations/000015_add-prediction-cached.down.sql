@@ -0,0 +1 @@
+ALTER TABLE predictions DROP COLUMN IF EXISTS cached;
@@ -0,0 +1,3 @@
+-- cached marks a prediction row written for a forecast served from the prediction cache, so every
+-- request stays in the audit trail while accuracy only counts each forecast once
+ALTER TABLE predictions ADD COLUMN cached BOOLEAN NOT NULL DEFAULT false;
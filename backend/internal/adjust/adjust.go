@@ -0,0 +1,67 @@
+package adjust
+
+import (
+	"math"
+	"sort"
+
+	"stockcast/internal/store"
+)
+
+// Apply back-adjusts prices and volumes for bonus issues, splits and rights issues so the series
+// has no artificial drops. Rows dated on or before a record date are scaled by that action,
+// stocks must be ordered oldest first. Cash dividends are not adjusted for.
+func Apply(stocks []*store.Stock, actions []*store.CorporateAction) []*store.Stock {
+	if len(actions) == 0 {
+		return stocks
+	}
+
+	// walk both newest first so every action is applied once the rows reach its record date
+	sorted := make([]*store.CorporateAction, len(actions))
+	copy(sorted, actions)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].RecordDate.After(sorted[j].RecordDate)
+	})
+
+	adjusted := make([]*store.Stock, len(stocks))
+	priceFactor, volumeFactor := 1.0, 1.0
+	next := 0
+	for i := len(stocks) - 1; i >= 0; i-- {
+		stock := stocks[i]
+		for next < len(sorted) && !stock.Date.After(sorted[next].RecordDate) {
+			f := factor(sorted[next], stock.Closep)
+			priceFactor *= f
+			volumeFactor /= f
+			next++
+		}
+
+		row := *stock
+		row.Ltp *= priceFactor
+		row.High *= priceFactor
+		row.Low *= priceFactor
+		row.Openp *= priceFactor
+		row.Closep *= priceFactor
+		row.Ycp *= priceFactor
+		row.Volume = int(math.Round(float64(stock.Volume) * volumeFactor))
+		adjusted[i] = &row
+	}
+	return adjusted
+}
+
+// factor returns the price multiplier for rows before the action, given the cum-entitlement close
+func factor(action *store.CorporateAction, cumClose float64) float64 {
+	switch action.Type {
+	case store.ActionBonus:
+		return 1 / (1 + action.Ratio)
+	case store.ActionSplit:
+		if action.Ratio > 0 {
+			return 1 / action.Ratio
+		}
+	case store.ActionRights:
+		// theoretical ex-rights price over the cum-rights close
+		if action.IssuePrice != nil && cumClose > 0 {
+			issuePrice := *action.IssuePrice
+			return (cumClose + action.Ratio*issuePrice) / ((1 + action.Ratio) * cumClose)
+		}
+	}
+	return 1
+}
@@ -0,0 +1,126 @@
+package adjust
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"stockcast/internal/store"
+)
+
+func day(d int) time.Time {
+	return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC)
+}
+
+func stocks(closes ...float64) []*store.Stock {
+	rows := make([]*store.Stock, len(closes))
+	for i, closep := range closes {
+		rows[i] = &store.Stock{
+			Date:   day(i + 1),
+			Ltp:    closep,
+			High:   closep,
+			Low:    closep,
+			Openp:  closep,
+			Closep: closep,
+			Ycp:    closep,
+			Volume: 1000,
+		}
+	}
+	return rows
+}
+
+func TestApply(t *testing.T) {
+	issuePrice := 10.0
+
+	tests := []struct {
+		name        string
+		stocks      []*store.Stock
+		actions     []*store.CorporateAction
+		wantCloses  []float64
+		wantVolumes []int
+	}{
+		{
+			name:        "no actions",
+			stocks:      stocks(100, 100),
+			wantCloses:  []float64{100, 100},
+			wantVolumes: []int{1000, 1000},
+		},
+		{
+			name:        "two for one split",
+			stocks:      stocks(100, 100, 50),
+			actions:     []*store.CorporateAction{{RecordDate: day(2), Type: store.ActionSplit, Ratio: 2}},
+			wantCloses:  []float64{50, 50, 50},
+			wantVolumes: []int{2000, 2000, 1000},
+		},
+		{
+			name:        "split with no ratio is ignored",
+			stocks:      stocks(100, 50),
+			actions:     []*store.CorporateAction{{RecordDate: day(1), Type: store.ActionSplit}},
+			wantCloses:  []float64{100, 50},
+			wantVolumes: []int{1000, 1000},
+		},
+		{
+			name:        "25 percent bonus",
+			stocks:      stocks(125, 100),
+			actions:     []*store.CorporateAction{{RecordDate: day(1), Type: store.ActionBonus, Ratio: 0.25}},
+			wantCloses:  []float64{100, 100},
+			wantVolumes: []int{1250, 1000},
+		},
+		{
+			// theoretical ex-rights price (100 + 1*10) / 2 = 55
+			name:        "one for one rights",
+			stocks:      stocks(100, 55),
+			actions:     []*store.CorporateAction{{RecordDate: day(1), Type: store.ActionRights, Ratio: 1, IssuePrice: &issuePrice}},
+			wantCloses:  []float64{55, 55},
+			wantVolumes: []int{1818, 1000},
+		},
+		{
+			name:        "cash dividend is not adjusted",
+			stocks:      stocks(100, 95),
+			actions:     []*store.CorporateAction{{RecordDate: day(1), Type: store.ActionCash, CashDividend: 5}},
+			wantCloses:  []float64{100, 95},
+			wantVolumes: []int{1000, 1000},
+		},
+		{
+			name:   "actions compound in any order",
+			stocks: stocks(200, 100, 50),
+			actions: []*store.CorporateAction{
+				{RecordDate: day(1), Type: store.ActionSplit, Ratio: 2},
+				{RecordDate: day(2), Type: store.ActionSplit, Ratio: 2},
+			},
+			wantCloses:  []float64{50, 50, 50},
+			wantVolumes: []int{4000, 2000, 1000},
+		},
+		{
+			name:        "action after the last row adjusts every row",
+			stocks:      stocks(100, 100),
+			actions:     []*store.CorporateAction{{RecordDate: day(10), Type: store.ActionSplit, Ratio: 4}},
+			wantCloses:  []float64{25, 25},
+			wantVolumes: []int{4000, 4000},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Apply(tt.stocks, tt.actions)
+			if len(got) != len(tt.wantCloses) {
+				t.Fatalf("Apply returned %d rows, want %d", len(got), len(tt.wantCloses))
+			}
+			for i, row := range got {
+				if math.Abs(row.Closep-tt.wantCloses[i]) > 1e-9 || math.Abs(row.High-tt.wantCloses[i]) > 1e-9 {
+					t.Errorf("row %d close = %v high = %v, want %v", i, row.Closep, row.High, tt.wantCloses[i])
+				}
+				if row.Volume != tt.wantVolumes[i] {
+					t.Errorf("row %d volume = %d, want %d", i, row.Volume, tt.wantVolumes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyLeavesInputUnchanged(t *testing.T) {
+	rows := stocks(100, 50)
+	Apply(rows, []*store.CorporateAction{{RecordDate: day(1), Type: store.ActionSplit, Ratio: 2}})
+	if rows[0].Closep != 100 || rows[0].Volume != 1000 {
+		t.Errorf("input row changed to close %v volume %d", rows[0].Closep, rows[0].Volume)
+	}
+}
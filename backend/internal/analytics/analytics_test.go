@@ -0,0 +1,157 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"stockcast/internal/store"
+)
+
+func day(d int) time.Time {
+	return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC)
+}
+
+func f(v float64) *float64 {
+	return &v
+}
+
+// line compares a series to want, where nil entries must be nil
+func line(t *testing.T, name string, got, want []*float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s has %d values, want %d", name, len(got), len(want))
+	}
+	for i := range got {
+		switch {
+		case got[i] == nil && want[i] == nil:
+		case got[i] == nil || want[i] == nil:
+			t.Errorf("%s[%d] = %v, want %v", name, i, got[i], want[i])
+		case math.Abs(*got[i]-*want[i]) > 1e-9:
+			t.Errorf("%s[%d] = %v, want %v", name, i, *got[i], *want[i])
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name        string
+		stocks      []*store.Stock
+		want        map[string][]*float64
+		wantMissing map[string]int
+	}{
+		{
+			name: "rebased to the first close",
+			stocks: []*store.Stock{
+				{Date: day(1), TradingCode: "A", Closep: 10},
+				{Date: day(1), TradingCode: "B", Closep: 50},
+				{Date: day(2), TradingCode: "A", Closep: 12},
+				{Date: day(2), TradingCode: "B", Closep: 40},
+			},
+			want: map[string][]*float64{
+				"A": {f(100), f(120)},
+				"B": {f(100), f(80)},
+			},
+			wantMissing: map[string]int{},
+		},
+		{
+			name: "late listing and a gap",
+			stocks: []*store.Stock{
+				{Date: day(1), TradingCode: "A", Closep: 10},
+				{Date: day(2), TradingCode: "A", Closep: 11},
+				{Date: day(2), TradingCode: "B", Closep: 20},
+				{Date: day(3), TradingCode: "A", Closep: 12},
+			},
+			want: map[string][]*float64{
+				"A": {f(100), f(110), f(120)},
+				"B": {nil, f(100), f(100)},
+			},
+			wantMissing: map[string]int{"B": 1},
+		},
+		{
+			name: "zero close is treated as missing",
+			stocks: []*store.Stock{
+				{Date: day(1), TradingCode: "A", Closep: 0},
+				{Date: day(2), TradingCode: "A", Closep: 5},
+			},
+			want:        map[string][]*float64{"A": {nil, f(100)}},
+			wantMissing: map[string]int{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var codes []string
+			for code := range tt.want {
+				codes = append(codes, code)
+			}
+			got := Compare(tt.stocks, codes, 100)
+			for code, want := range tt.want {
+				line(t, code, got.Series[code], want)
+				if got.MissingDays[code] != tt.wantMissing[code] {
+					t.Errorf("MissingDays[%s] = %d, want %d", code, got.MissingDays[code], tt.wantMissing[code])
+				}
+			}
+		})
+	}
+}
+
+func TestLogReturns(t *testing.T) {
+	tests := []struct {
+		name   string
+		closes []*float64
+		want   []*float64
+	}{
+		{name: "empty", closes: nil, want: []*float64{}},
+		{name: "doubling", closes: []*float64{f(1), f(2)}, want: []*float64{nil, f(math.Ln2)}},
+		{name: "gap", closes: []*float64{f(1), nil, f(2)}, want: []*float64{nil, nil, nil}},
+		{name: "zero close", closes: []*float64{f(0), f(2)}, want: []*float64{nil, nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line(t, "returns", LogReturns(tt.closes), tt.want)
+		})
+	}
+}
+
+func TestPearson(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  []*float64
+		want  *float64
+		wantN int
+	}{
+		{name: "perfectly correlated", a: []*float64{f(1), f(2), f(3)}, b: []*float64{f(2), f(4), f(6)}, want: f(1), wantN: 3},
+		{name: "inversely correlated", a: []*float64{f(1), f(2), f(3)}, b: []*float64{f(3), f(2), f(1)}, want: f(-1), wantN: 3},
+		{name: "too few pairs", a: []*float64{f(1), f(2), nil}, b: []*float64{f(1), f(2), f(3)}, want: nil, wantN: 2},
+		{name: "no variance", a: []*float64{f(1), f(1), f(1)}, b: []*float64{f(1), f(2), f(3)}, want: nil, wantN: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, n := Pearson(tt.a, tt.b)
+			if n != tt.wantN {
+				t.Errorf("Pearson used %d pairs, want %d", n, tt.wantN)
+			}
+			line(t, "correlation", []*float64{got}, []*float64{tt.want})
+		})
+	}
+}
+
+func TestCorrelate(t *testing.T) {
+	var stocks []*store.Stock
+	for i, closep := range []float64{10, 11, 10, 12, 13} {
+		stocks = append(stocks,
+			&store.Stock{Date: day(i + 1), TradingCode: "A", Closep: closep},
+			&store.Stock{Date: day(i + 1), TradingCode: "B", Closep: closep * 2},
+		)
+	}
+
+	got := Correlate(stocks, []string{"A", "B"})
+	for i := range 2 {
+		for j := range 2 {
+			line(t, "matrix", []*float64{got.Matrix[i][j]}, []*float64{f(1)})
+			if got.Observations[i][j] != 4 {
+				t.Errorf("Observations[%d][%d] = %d, want 4", i, j, got.Observations[i][j])
+			}
+		}
+	}
+}
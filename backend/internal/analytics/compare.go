@@ -0,0 +1,81 @@
+package analytics
+
+import (
+	"time"
+
+	"stockcast/internal/store"
+)
+
+// Comparison holds rebased closing prices of several codes aligned on the same dates.
+// Values are nil before a code's first trade in the range, later gaps carry the last close forward.
+type Comparison struct {
+	Dates       []time.Time           `json:"dates"`
+	Series      map[string][]*float64 `json:"series"`
+	MissingDays map[string]int        `json:"missingDays"`
+}
+
+// Dates returns the sorted union of trading dates found in stocks, which must be ordered by date
+func Dates(stocks []*store.Stock) []time.Time {
+	var dates []time.Time
+	for _, stock := range stocks {
+		if len(dates) == 0 || !dates[len(dates)-1].Equal(stock.Date) {
+			dates = append(dates, stock.Date)
+		}
+	}
+	return dates
+}
+
+// Closes lays the closing prices of each code out on dates, missing days are nil
+func Closes(stocks []*store.Stock, codes []string, dates []time.Time) map[string][]*float64 {
+	index := make(map[time.Time]int, len(dates))
+	for i, date := range dates {
+		index[date] = i
+	}
+
+	closes := make(map[string][]*float64, len(codes))
+	for _, code := range codes {
+		closes[code] = make([]*float64, len(dates))
+	}
+	for _, stock := range stocks {
+		line, ok := closes[stock.TradingCode]
+		if !ok {
+			continue
+		}
+		closep := stock.Closep
+		line[index[stock.Date]] = &closep
+	}
+	return closes
+}
+
+// Compare rebases every code to base on its first trading day in the range, so the series read as cumulative returns
+func Compare(stocks []*store.Stock, codes []string, base float64) Comparison {
+	dates := Dates(stocks)
+	closes := Closes(stocks, codes, dates)
+
+	comparison := Comparison{
+		Dates:       dates,
+		Series:      make(map[string][]*float64, len(codes)),
+		MissingDays: make(map[string]int, len(codes)),
+	}
+	for _, code := range codes {
+		line := make([]*float64, len(dates))
+		var first, last *float64
+		for i, closep := range closes[code] {
+			switch {
+			case closep != nil && *closep > 0:
+				if first == nil {
+					first = closep
+				}
+				last = closep
+			case first != nil:
+				comparison.MissingDays[code]++
+			default:
+				continue
+			}
+			value := base * *last / *first
+			line[i] = &value
+		}
+		comparison.Series[code] = line
+	}
+	return comparison
+}
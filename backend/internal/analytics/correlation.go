@@ -0,0 +1,120 @@
+package analytics
+
+import (
+	"math"
+	"time"
+
+	"stockcast/internal/store"
+)
+
+// minObservations is the fewest paired returns a correlation is reported for
+const minObservations = 3
+
+type CorrelationMatrix struct {
+	Codes        []string     `json:"codes"`
+	Matrix       [][]*float64 `json:"matrix"`
+	Observations [][]int      `json:"observations"`
+}
+
+type RollingCorrelation struct {
+	Codes  [2]string   `json:"codes"`
+	Window int         `json:"window"`
+	Dates  []time.Time `json:"dates"`
+	Values []*float64  `json:"values"`
+}
+
+// LogReturns returns the daily log returns of closes, nil where either day is missing
+func LogReturns(closes []*float64) []*float64 {
+	returns := make([]*float64, len(closes))
+	for i := 1; i < len(closes); i++ {
+		prev, cur := closes[i-1], closes[i]
+		if prev == nil || cur == nil || *prev <= 0 || *cur <= 0 {
+			continue
+		}
+		r := math.Log(*cur / *prev)
+		returns[i] = &r
+	}
+	return returns
+}
+
+// Pearson returns the correlation of a and b over the positions where both are present,
+// along with the number of pairs used. The result is nil when there are too few pairs or no variance.
+func Pearson(a, b []*float64) (*float64, int) {
+	var n, sumA, sumB, sumAA, sumBB, sumAB float64
+	for i := range a {
+		if a[i] == nil || b[i] == nil {
+			continue
+		}
+		x, y := *a[i], *b[i]
+		n++
+		sumA += x
+		sumB += y
+		sumAA += x * x
+		sumBB += y * y
+		sumAB += x * y
+	}
+	if n < minObservations {
+		return nil, int(n)
+	}
+
+	cov := sumAB - sumA*sumB/n
+	varA := sumAA - sumA*sumA/n
+	varB := sumBB - sumB*sumB/n
+	if varA <= 0 || varB <= 0 {
+		return nil, int(n)
+	}
+	corr := math.Max(-1, math.Min(1, cov/math.Sqrt(varA*varB)))
+	return &corr, int(n)
+}
+
+// Correlate builds the Pearson correlation matrix of daily log returns between codes
+func Correlate(stocks []*store.Stock, codes []string) CorrelationMatrix {
+	dates := Dates(stocks)
+	closes := Closes(stocks, codes, dates)
+
+	returns := make([][]*float64, len(codes))
+	for i, code := range codes {
+		returns[i] = LogReturns(closes[code])
+	}
+
+	m := CorrelationMatrix{
+		Codes:        codes,
+		Matrix:       make([][]*float64, len(codes)),
+		Observations: make([][]int, len(codes)),
+	}
+	for i := range codes {
+		m.Matrix[i] = make([]*float64, len(codes))
+		m.Observations[i] = make([]int, len(codes))
+	}
+	for i := range codes {
+		for j := i; j < len(codes); j++ {
+			corr, n := Pearson(returns[i], returns[j])
+			m.Matrix[i][j], m.Matrix[j][i] = corr, corr
+			m.Observations[i][j], m.Observations[j][i] = n, n
+		}
+	}
+	return m
+}
+
+// Rolling computes the correlation of daily log returns between two codes over a trailing window of trading days
+func Rolling(stocks []*store.Stock, a, b string, window int) RollingCorrelation {
+	dates := Dates(stocks)
+	closes := Closes(stocks, []string{a, b}, dates)
+	returnsA := LogReturns(closes[a])
+	returnsB := LogReturns(closes[b])
+
+	rolling := RollingCorrelation{
+		Codes:  [2]string{a, b},
+		Window: window,
+		Dates:  dates,
+		Values: make([]*float64, len(dates)),
+	}
+	for i := window; i < len(dates); i++ {
+		// a window needs at least half of its days paired to be meaningful
+		corr, n := Pearson(returnsA[i-window+1:i+1], returnsB[i-window+1:i+1])
+		if n*2 >= window {
+			rolling.Values[i] = corr
+		}
+	}
+	return rolling
+}
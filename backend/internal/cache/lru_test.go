@@ -0,0 +1,90 @@
+package cache
+
+import "testing"
+
+func TestLRU(t *testing.T) {
+	type op struct {
+		add   bool
+		key   string
+		value int
+	}
+
+	tests := []struct {
+		name    string
+		size    int
+		ops     []op
+		want    map[string]int
+		missing []string
+	}{
+		{
+			name: "under capacity",
+			size: 2,
+			ops:  []op{{add: true, key: "a", value: 1}, {add: true, key: "b", value: 2}},
+			want: map[string]int{"a": 1, "b": 2},
+		},
+		{
+			name:    "evicts the oldest",
+			size:    2,
+			ops:     []op{{add: true, key: "a", value: 1}, {add: true, key: "b", value: 2}, {add: true, key: "c", value: 3}},
+			want:    map[string]int{"b": 2, "c": 3},
+			missing: []string{"a"},
+		},
+		{
+			name: "get marks an entry as recently used",
+			size: 2,
+			ops: []op{
+				{add: true, key: "a", value: 1},
+				{add: true, key: "b", value: 2},
+				{key: "a"},
+				{add: true, key: "c", value: 3},
+			},
+			want:    map[string]int{"a": 1, "c": 3},
+			missing: []string{"b"},
+		},
+		{
+			name: "adding an existing key replaces it and marks it as recently used",
+			size: 2,
+			ops: []op{
+				{add: true, key: "a", value: 1},
+				{add: true, key: "b", value: 2},
+				{add: true, key: "a", value: 10},
+				{add: true, key: "c", value: 3},
+			},
+			want:    map[string]int{"a": 10, "c": 3},
+			missing: []string{"b"},
+		},
+		{
+			name:    "size zero disables the cache",
+			size:    0,
+			ops:     []op{{add: true, key: "a", value: 1}},
+			want:    map[string]int{},
+			missing: []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewLRU[string, int](tt.size)
+			for _, o := range tt.ops {
+				if o.add {
+					c.Add(o.key, o.value)
+				} else {
+					c.Get(o.key)
+				}
+			}
+
+			if c.Len() != len(tt.want) {
+				t.Errorf("Len() = %d, want %d", c.Len(), len(tt.want))
+			}
+			for key, want := range tt.want {
+				if got, ok := c.Get(key); !ok || got != want {
+					t.Errorf("Get(%q) = %d, %v, want %d, true", key, got, ok, want)
+				}
+			}
+			for _, key := range tt.missing {
+				if _, ok := c.Get(key); ok {
+					t.Errorf("Get(%q) found an entry that should have been evicted", key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package calendar
+
+import (
+	"time"
+
+	"stockcast/internal/store"
+)
+
+// Calendar answers trading day questions for DSE, which trades Sunday to Thursday
+// except on the holidays listed in the trading_calendar table
+type Calendar struct {
+	exceptions map[time.Time]*store.CalendarDay
+}
+
+// Day is a calendar date with its trading status
+type Day struct {
+	Date         time.Time `json:"date"`
+	IsTradingDay bool      `json:"isTradingDay"`
+	Description  string    `json:"description,omitempty"`
+}
+
+func New(days []*store.CalendarDay) *Calendar {
+	exceptions := make(map[time.Time]*store.CalendarDay, len(days))
+	for _, day := range days {
+		exceptions[Date(day.Date)] = day
+	}
+	return &Calendar{exceptions: exceptions}
+}
+
+// Date truncates t to midnight UTC of its calendar date
+func Date(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func (c *Calendar) IsTradingDay(t time.Time) bool {
+	date := Date(t)
+	if day, ok := c.exceptions[date]; ok {
+		return day.IsTradingDay
+	}
+	weekday := date.Weekday()
+	return weekday != time.Friday && weekday != time.Saturday
+}
+
+// NextTradingDay returns the first trading day strictly after t
+func (c *Calendar) NextTradingDay(t time.Time) time.Time {
+	return c.AddTradingDays(t, 1)
+}
+
+// PrevTradingDay returns the last trading day strictly before t
+func (c *Calendar) PrevTradingDay(t time.Time) time.Time {
+	return c.AddTradingDays(t, -1)
+}
+
+// AddTradingDays moves n trading days forward from t, or backwards when n is negative.
+// With n = 0 it returns t's date unchanged, whether or not it is a trading day.
+func (c *Calendar) AddTradingDays(t time.Time, n int) time.Time {
+	date := Date(t)
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for n > 0 {
+		date = date.AddDate(0, 0, step)
+		if c.IsTradingDay(date) {
+			n--
+		}
+	}
+	return date
+}
+
+// TradingDaysBetween counts the trading days from start to end, both included
+func (c *Calendar) TradingDaysBetween(start, end time.Time) int {
+	count := 0
+	for date := Date(start); !date.After(Date(end)); date = date.AddDate(0, 0, 1) {
+		if c.IsTradingDay(date) {
+			count++
+		}
+	}
+	return count
+}
+
+// Year lists every date of the year with its trading status
+func (c *Calendar) Year(year int) []Day {
+	var days []Day
+	for date := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC); date.Year() == year; date = date.AddDate(0, 0, 1) {
+		day := Day{Date: date, IsTradingDay: c.IsTradingDay(date)}
+		if exception, ok := c.exceptions[date]; ok {
+			day.Description = exception.Description
+		} else if !day.IsTradingDay {
+			day.Description = "weekend"
+		}
+		days = append(days, day)
+	}
+	return days
+}
@@ -0,0 +1,117 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"stockcast/internal/store"
+)
+
+// 2024-01-04 is a Thursday, DSE weekends are Friday and Saturday
+func date(month time.Month, d int) time.Time {
+	return time.Date(2024, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func testCalendar() *Calendar {
+	return New([]*store.CalendarDay{
+		// a Sunday holiday right after the weekend
+		{Date: date(time.January, 7), IsTradingDay: false, Description: "holiday"},
+		// a Saturday made into a trading day
+		{Date: date(time.January, 13), IsTradingDay: true, Description: "special session"},
+	})
+}
+
+func TestIsTradingDay(t *testing.T) {
+	cal := testCalendar()
+	tests := []struct {
+		name string
+		date time.Time
+		want bool
+	}{
+		{name: "thursday", date: date(time.January, 4), want: true},
+		{name: "friday", date: date(time.January, 5), want: false},
+		{name: "saturday", date: date(time.January, 6), want: false},
+		{name: "sunday holiday", date: date(time.January, 7), want: false},
+		{name: "monday", date: date(time.January, 8), want: true},
+		{name: "saturday session", date: date(time.January, 13), want: true},
+		{name: "time of day is ignored", date: date(time.January, 8).Add(15 * time.Hour), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cal.IsTradingDay(tt.date); got != tt.want {
+				t.Errorf("IsTradingDay(%s) = %v, want %v", tt.date.Format("2006-01-02"), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddTradingDays(t *testing.T) {
+	cal := testCalendar()
+	tests := []struct {
+		name  string
+		start time.Time
+		n     int
+		want  time.Time
+	}{
+		{name: "zero keeps a weekend date", start: date(time.January, 5), n: 0, want: date(time.January, 5)},
+		{name: "next day", start: date(time.January, 1), n: 1, want: date(time.January, 2)},
+		{name: "over the weekend and a holiday", start: date(time.January, 4), n: 1, want: date(time.January, 8)},
+		{name: "from a weekend", start: date(time.January, 6), n: 1, want: date(time.January, 8)},
+		{name: "onto a saturday session", start: date(time.January, 11), n: 1, want: date(time.January, 13)},
+		{name: "several days", start: date(time.January, 4), n: 3, want: date(time.January, 10)},
+		{name: "backwards over the weekend and a holiday", start: date(time.January, 8), n: -1, want: date(time.January, 4)},
+		{name: "backwards from a weekend", start: date(time.January, 6), n: -2, want: date(time.January, 3)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cal.AddTradingDays(tt.start, tt.n); !got.Equal(tt.want) {
+				t.Errorf("AddTradingDays(%s, %d) = %s, want %s",
+					tt.start.Format("2006-01-02"), tt.n, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestNextAndPrevTradingDay(t *testing.T) {
+	cal := testCalendar()
+	tests := []struct {
+		name     string
+		date     time.Time
+		wantNext time.Time
+		wantPrev time.Time
+	}{
+		{name: "weekday", date: date(time.January, 2), wantNext: date(time.January, 3), wantPrev: date(time.January, 1)},
+		{name: "holiday", date: date(time.January, 7), wantNext: date(time.January, 8), wantPrev: date(time.January, 4)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cal.NextTradingDay(tt.date); !got.Equal(tt.wantNext) {
+				t.Errorf("NextTradingDay = %s, want %s", got.Format("2006-01-02"), tt.wantNext.Format("2006-01-02"))
+			}
+			if got := cal.PrevTradingDay(tt.date); !got.Equal(tt.wantPrev) {
+				t.Errorf("PrevTradingDay = %s, want %s", got.Format("2006-01-02"), tt.wantPrev.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestTradingDaysBetween(t *testing.T) {
+	cal := testCalendar()
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       int
+	}{
+		{name: "same trading day", start: date(time.January, 4), end: date(time.January, 4), want: 1},
+		{name: "weekend only", start: date(time.January, 5), end: date(time.January, 6), want: 0},
+		{name: "week with a holiday", start: date(time.January, 4), end: date(time.January, 11), want: 5},
+		{name: "end before start", start: date(time.January, 11), end: date(time.January, 4), want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cal.TradingDaysBetween(tt.start, tt.end); got != tt.want {
+				t.Errorf("TradingDaysBetween = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package candles
+
+import (
+	"fmt"
+	"time"
+
+	"stockcast/internal/store"
+)
+
+type Interval string
+
+const (
+	Daily     Interval = "1d"
+	Weekly    Interval = "1w"
+	Monthly   Interval = "1M"
+	Quarterly Interval = "1Q"
+)
+
+// ParseInterval validates the interval query value, an empty value means daily rows
+func ParseInterval(s string) (Interval, error) {
+	switch Interval(s) {
+	case "", Daily:
+		return Daily, nil
+	case Weekly, Monthly, Quarterly:
+		return Interval(s), nil
+	}
+	return "", fmt.Errorf("interval must be one of %s, %s, %s or %s", Daily, Weekly, Monthly, Quarterly)
+}
+
+// PeriodStart returns the first calendar day of the period t falls in.
+// DSE trades Sunday to Thursday, so trading weeks start on Sunday.
+func (i Interval) PeriodStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch i {
+	case Weekly:
+		return time.Date(y, m, d-int(t.Weekday()), 0, 0, 0, 0, t.Location())
+	case Monthly:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	case Quarterly:
+		return time.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// Resample aggregates daily rows ordered oldest first into candles of the given interval.
+// Each candle is dated on its first trading day and takes the first open and ycp, the max high,
+// the min low, the last close and ltp, and the summed trade, value and volume.
+func Resample(stocks []*store.Stock, interval Interval) []*store.Stock {
+	if interval == Daily {
+		return stocks
+	}
+
+	var candles []*store.Stock
+	var current *store.Stock
+	var currentPeriod time.Time
+	for _, stock := range stocks {
+		period := interval.PeriodStart(stock.Date)
+		if current == nil || !period.Equal(currentPeriod) {
+			candle := *stock
+			current = &candle
+			currentPeriod = period
+			candles = append(candles, current)
+			continue
+		}
+
+		current.ID = stock.ID
+		current.High = max(current.High, stock.High)
+		current.Low = min(current.Low, stock.Low)
+		current.Closep = stock.Closep
+		current.Ltp = stock.Ltp
+		current.Trade += stock.Trade
+		current.Value += stock.Value
+		current.Volume += stock.Volume
+	}
+	return candles
+}
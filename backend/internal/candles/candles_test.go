@@ -0,0 +1,116 @@
+package candles
+
+import (
+	"testing"
+	"time"
+
+	"stockcast/internal/store"
+)
+
+func date(month time.Month, d int) time.Time {
+	return time.Date(2024, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Interval
+		wantErr bool
+	}{
+		{in: "", want: Daily},
+		{in: "1d", want: Daily},
+		{in: "1w", want: Weekly},
+		{in: "1M", want: Monthly},
+		{in: "1Q", want: Quarterly},
+		{in: "1m", wantErr: true},
+		{in: "1y", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseInterval(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseInterval(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseInterval(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPeriodStart(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval Interval
+		date     time.Time
+		want     time.Time
+	}{
+		{name: "daily", interval: Daily, date: date(time.May, 15).Add(10 * time.Hour), want: date(time.May, 15)},
+		{name: "weekly from a thursday", interval: Weekly, date: date(time.May, 16), want: date(time.May, 12)},
+		{name: "weekly from a sunday", interval: Weekly, date: date(time.May, 12), want: date(time.May, 12)},
+		{name: "weekly across a month", interval: Weekly, date: date(time.May, 2), want: date(time.April, 28)},
+		{name: "monthly", interval: Monthly, date: date(time.May, 16), want: date(time.May, 1)},
+		{name: "quarterly", interval: Quarterly, date: date(time.May, 16), want: date(time.April, 1)},
+		{name: "quarterly first month", interval: Quarterly, date: date(time.January, 31), want: date(time.January, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.interval.PeriodStart(tt.date); !got.Equal(tt.want) {
+				t.Errorf("PeriodStart = %s, want %s", got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestResample(t *testing.T) {
+	// Wednesday and Thursday of one week, then Sunday of the next
+	rows := []*store.Stock{
+		{ID: 1, Date: date(time.May, 15), Openp: 10, High: 12, Low: 9, Closep: 11, Ltp: 11, Ycp: 10, Trade: 1, Value: 1, Volume: 100},
+		{ID: 2, Date: date(time.May, 16), Openp: 11, High: 14, Low: 8, Closep: 13, Ltp: 13, Ycp: 11, Trade: 2, Value: 2, Volume: 200},
+		{ID: 3, Date: date(time.May, 19), Openp: 13, High: 13, Low: 12, Closep: 12, Ltp: 12, Ycp: 13, Trade: 3, Value: 3, Volume: 300},
+	}
+
+	tests := []struct {
+		name     string
+		interval Interval
+		want     []store.Stock
+	}{
+		{
+			name:     "daily is unchanged",
+			interval: Daily,
+			want:     []store.Stock{*rows[0], *rows[1], *rows[2]},
+		},
+		{
+			name:     "weekly",
+			interval: Weekly,
+			want: []store.Stock{
+				{ID: 2, Date: date(time.May, 15), Openp: 10, High: 14, Low: 8, Closep: 13, Ltp: 13, Ycp: 10, Trade: 3, Value: 3, Volume: 300},
+				*rows[2],
+			},
+		},
+		{
+			name:     "monthly",
+			interval: Monthly,
+			want: []store.Stock{
+				{ID: 3, Date: date(time.May, 15), Openp: 10, High: 14, Low: 8, Closep: 12, Ltp: 12, Ycp: 10, Trade: 6, Value: 6, Volume: 600},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Resample(rows, tt.interval)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Resample returned %d candles, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if *got[i] != tt.want[i] {
+					t.Errorf("candle %d = %+v, want %+v", i, *got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	if rows[0].High != 12 || rows[0].Volume != 100 {
+		t.Errorf("Resample changed its input: %+v", *rows[0])
+	}
+}
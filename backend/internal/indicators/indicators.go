@@ -0,0 +1,257 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// warmupFactor is the number of periods fed into recursive indicators (EMA, RSI, MACD)
+// before the first requested row, so their seed no longer has a visible effect on the output.
+const warmupFactor = 5
+
+const (
+	KindSMA    = "sma"
+	KindEMA    = "ema"
+	KindRSI    = "rsi"
+	KindMACD   = "macd"
+	KindBBands = "bbands"
+)
+
+var defaultPeriods = map[string]int{
+	KindSMA:    20,
+	KindEMA:    20,
+	KindRSI:    14,
+	KindMACD:   26,
+	KindBBands: 20,
+}
+
+// MACD uses the conventional 12/26/9 parameters
+const (
+	macdFast   = 12
+	macdSlow   = 26
+	macdSignal = 9
+)
+
+// Bollinger bands are drawn this many standard deviations from the middle band
+const bbandsWidth = 2.0
+
+// Spec describes a single requested indicator such as "sma20" or "rsi14"
+type Spec struct {
+	Name   string
+	Kind   string
+	Period int
+}
+
+// Result holds the named lines of an indicator, e.g. "value" for an SMA or
+// "macd", "signal" and "histogram" for MACD. Entries are nil during warm-up.
+type Result map[string][]*float64
+
+// Parse reads an indicator name made of a kind and an optional period, e.g. "ema50" or "bbands"
+func Parse(name string) (Spec, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	kind := strings.TrimRight(name, "0123456789")
+
+	defaultPeriod, ok := defaultPeriods[kind]
+	if !ok {
+		return Spec{}, fmt.Errorf("unknown indicator %q", name)
+	}
+
+	period := defaultPeriod
+	if digits := name[len(kind):]; digits != "" {
+		if kind == KindMACD {
+			return Spec{}, fmt.Errorf("indicator %q does not take a period", kind)
+		}
+		p, err := strconv.Atoi(digits)
+		if err != nil || p < 2 || p > 500 {
+			return Spec{}, fmt.Errorf("indicator %q must have a period between 2 and 500", name)
+		}
+		period = p
+	}
+
+	return Spec{Name: name, Kind: kind, Period: period}, nil
+}
+
+// Warmup returns how many rows before the first requested row are needed for stable values
+func (s Spec) Warmup() int {
+	switch s.Kind {
+	case KindEMA, KindRSI:
+		return s.Period * warmupFactor
+	case KindMACD:
+		return macdSlow*warmupFactor + macdSignal
+	default:
+		return s.Period - 1
+	}
+}
+
+// Compute calculates the indicator over closing prices ordered oldest first
+func (s Spec) Compute(closes []float64) Result {
+	switch s.Kind {
+	case KindSMA:
+		return Result{"value": SMA(closes, s.Period)}
+	case KindEMA:
+		return Result{"value": EMA(closes, s.Period)}
+	case KindRSI:
+		return Result{"value": RSI(closes, s.Period)}
+	case KindMACD:
+		macd, signal, hist := MACD(closes, macdFast, macdSlow, macdSignal)
+		return Result{"macd": macd, "signal": signal, "histogram": hist}
+	case KindBBands:
+		upper, middle, lower := BollingerBands(closes, s.Period, bbandsWidth)
+		return Result{"upper": upper, "middle": middle, "lower": lower}
+	}
+	return Result{}
+}
+
+// Trim drops the first n entries of every line, used to cut the warm-up rows from the output
+func (r Result) Trim(n int) Result {
+	trimmed := make(Result, len(r))
+	for name, line := range r {
+		if n > len(line) {
+			n = len(line)
+		}
+		trimmed[name] = line[n:]
+	}
+	return trimmed
+}
+
+// SMA returns the simple moving average over period
+func SMA(values []float64, period int) []*float64 {
+	out := make([]*float64, len(values))
+	sum := 0.0
+	for i, v := range values {
+		sum += v
+		if i >= period {
+			sum -= values[i-period]
+		}
+		if i >= period-1 {
+			out[i] = ptr(sum / float64(period))
+		}
+	}
+	return out
+}
+
+// EMA returns the exponential moving average over period, seeded with the SMA of the first period values
+func EMA(values []float64, period int) []*float64 {
+	out := make([]*float64, len(values))
+	if len(values) < period {
+		return out
+	}
+
+	k := 2.0 / float64(period+1)
+	prev := 0.0
+	for i := 0; i < period; i++ {
+		prev += values[i]
+	}
+	prev /= float64(period)
+	out[period-1] = ptr(prev)
+
+	for i := period; i < len(values); i++ {
+		prev = values[i]*k + prev*(1-k)
+		out[i] = ptr(prev)
+	}
+	return out
+}
+
+// RSI returns the relative strength index over period using Wilder's smoothing
+func RSI(values []float64, period int) []*float64 {
+	out := make([]*float64, len(values))
+	if len(values) <= period {
+		return out
+	}
+
+	var avgGain, avgLoss float64
+	for i := 1; i <= period; i++ {
+		gain, loss := change(values[i-1], values[i])
+		avgGain += gain
+		avgLoss += loss
+	}
+	avgGain /= float64(period)
+	avgLoss /= float64(period)
+	out[period] = ptr(rsi(avgGain, avgLoss))
+
+	for i := period + 1; i < len(values); i++ {
+		gain, loss := change(values[i-1], values[i])
+		avgGain = (avgGain*float64(period-1) + gain) / float64(period)
+		avgLoss = (avgLoss*float64(period-1) + loss) / float64(period)
+		out[i] = ptr(rsi(avgGain, avgLoss))
+	}
+	return out
+}
+
+// MACD returns the MACD line, its signal line and the histogram between them
+func MACD(values []float64, fast, slow, signal int) (macd, signalLine, hist []*float64) {
+	macd = make([]*float64, len(values))
+	signalLine = make([]*float64, len(values))
+	hist = make([]*float64, len(values))
+
+	fastEMA := EMA(values, fast)
+	slowEMA := EMA(values, slow)
+
+	start := -1
+	var line []float64
+	for i := range values {
+		if fastEMA[i] == nil || slowEMA[i] == nil {
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+		macd[i] = ptr(*fastEMA[i] - *slowEMA[i])
+		line = append(line, *macd[i])
+	}
+	if start < 0 {
+		return macd, signalLine, hist
+	}
+
+	for j, s := range EMA(line, signal) {
+		if s == nil {
+			continue
+		}
+		signalLine[start+j] = s
+		hist[start+j] = ptr(*macd[start+j] - *s)
+	}
+	return macd, signalLine, hist
+}
+
+// BollingerBands returns the upper, middle and lower bands, width standard deviations around the SMA
+func BollingerBands(values []float64, period int, width float64) (upper, middle, lower []*float64) {
+	upper = make([]*float64, len(values))
+	lower = make([]*float64, len(values))
+	middle = SMA(values, period)
+
+	for i := range values {
+		if middle[i] == nil {
+			continue
+		}
+		mean := *middle[i]
+		variance := 0.0
+		for _, v := range values[i-period+1 : i+1] {
+			variance += (v - mean) * (v - mean)
+		}
+		sd := math.Sqrt(variance / float64(period))
+		upper[i] = ptr(mean + width*sd)
+		lower[i] = ptr(mean - width*sd)
+	}
+	return upper, middle, lower
+}
+
+func change(prev, cur float64) (gain, loss float64) {
+	diff := cur - prev
+	if diff > 0 {
+		return diff, 0
+	}
+	return 0, -diff
+}
+
+func rsi(avgGain, avgLoss float64) float64 {
+	if avgLoss == 0 {
+		return 100
+	}
+	return 100 - 100/(1+avgGain/avgLoss)
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
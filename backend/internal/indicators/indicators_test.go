@@ -0,0 +1,217 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+)
+
+// values dereferences line for comparison and printing, warm-up rows stay nil
+func values(line []*float64) []any {
+	out := make([]any, len(line))
+	for i, v := range line {
+		if v != nil {
+			out[i] = *v
+		}
+	}
+	return out
+}
+
+// equal compares line to want, where want holds nil for warm-up rows and float64 otherwise
+func equal(got []*float64, want []any) bool {
+	g := values(got)
+	if len(g) != len(want) {
+		return false
+	}
+	for i := range g {
+		if want[i] == nil || g[i] == nil {
+			if want[i] != g[i] {
+				return false
+			}
+			continue
+		}
+		if math.Abs(g[i].(float64)-want[i].(float64)) > 1e-6 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Spec
+		wantErr bool
+	}{
+		{name: "sma", want: Spec{Name: "sma", Kind: KindSMA, Period: 20}},
+		{name: " EMA50 ", want: Spec{Name: "ema50", Kind: KindEMA, Period: 50}},
+		{name: "rsi14", want: Spec{Name: "rsi14", Kind: KindRSI, Period: 14}},
+		{name: "macd", want: Spec{Name: "macd", Kind: KindMACD, Period: 26}},
+		{name: "macd12", wantErr: true},
+		{name: "sma1", wantErr: true},
+		{name: "sma501", wantErr: true},
+		{name: "vwap", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSMA(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		period int
+		want   []any
+	}{
+		{name: "empty", values: nil, period: 3, want: []any{}},
+		{name: "shorter than period", values: []float64{1, 2}, period: 3, want: []any{nil, nil}},
+		{name: "exactly period", values: []float64{1, 2, 3}, period: 3, want: []any{nil, nil, 2.0}},
+		{name: "rolling", values: []float64{1, 2, 3, 4, 5}, period: 3, want: []any{nil, nil, 2.0, 3.0, 4.0}},
+		{name: "period one", values: []float64{4, 5}, period: 1, want: []any{4.0, 5.0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SMA(tt.values, tt.period); !equal(got, tt.want) {
+				t.Errorf("SMA = %v, want %v", values(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestEMA(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		period int
+		want   []any
+	}{
+		{name: "shorter than period", values: []float64{1, 2}, period: 3, want: []any{nil, nil}},
+		{name: "seeded with sma", values: []float64{1, 2, 3}, period: 3, want: []any{nil, nil, 2.0}},
+		// k = 0.5, 6*0.5 + 2*0.5 = 4, 4*0.5 + 4*0.5 = 4
+		{name: "smoothed", values: []float64{1, 2, 3, 6, 4}, period: 3, want: []any{nil, nil, 2.0, 4.0, 4.0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EMA(tt.values, tt.period); !equal(got, tt.want) {
+				t.Errorf("EMA = %v, want %v", values(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestRSI(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		period int
+		want   []any
+	}{
+		{name: "shorter than period", values: []float64{1, 2, 3}, period: 3, want: []any{nil, nil, nil}},
+		{name: "only gains", values: []float64{1, 2, 3, 4}, period: 3, want: []any{nil, nil, nil, 100.0}},
+		{name: "only losses", values: []float64{4, 3, 2, 1}, period: 3, want: []any{nil, nil, nil, 0.0}},
+		// gains 2 and losses 1 over two days, then a loss of 1: (1*1+0)/2 = 0.5 gain, (0.5*1+1)/2 = 0.75 loss
+		{name: "wilder smoothing", values: []float64{10, 12, 11, 10}, period: 2, want: []any{nil, nil, 100 - 100/(1+1/0.5), 100 - 100/(1+0.5/0.75)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RSI(tt.values, tt.period); !equal(got, tt.want) {
+				t.Errorf("RSI = %v, want %v", values(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestMACDShorterThanSlowPeriod(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+	}{
+		{name: "empty", values: nil},
+		{name: "shorter than slow period", values: make([]float64, macdSlow-1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			macd, signal, hist := MACD(tt.values, macdFast, macdSlow, macdSignal)
+			for i := range tt.values {
+				if macd[i] != nil || signal[i] != nil || hist[i] != nil {
+					t.Fatalf("row %d has a value during warm-up", i)
+				}
+			}
+		})
+	}
+}
+
+func TestBollingerBands(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     []float64
+		period     int
+		wantUpper  []any
+		wantMiddle []any
+		wantLower  []any
+	}{
+		{
+			name:       "shorter than period",
+			values:     []float64{1, 2},
+			period:     3,
+			wantUpper:  []any{nil, nil},
+			wantMiddle: []any{nil, nil},
+			wantLower:  []any{nil, nil},
+		},
+		{
+			name:       "flat prices",
+			values:     []float64{5, 5, 5},
+			period:     3,
+			wantUpper:  []any{nil, nil, 5.0},
+			wantMiddle: []any{nil, nil, 5.0},
+			wantLower:  []any{nil, nil, 5.0},
+		},
+		{
+			// mean 3, population standard deviation 1
+			name:       "two deviations",
+			values:     []float64{2, 4},
+			period:     2,
+			wantUpper:  []any{nil, 5.0},
+			wantMiddle: []any{nil, 3.0},
+			wantLower:  []any{nil, 1.0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upper, middle, lower := BollingerBands(tt.values, tt.period, bbandsWidth)
+			if !equal(upper, tt.wantUpper) || !equal(middle, tt.wantMiddle) || !equal(lower, tt.wantLower) {
+				t.Errorf("BollingerBands = %v %v %v, want %v %v %v",
+					values(upper), values(middle), values(lower), tt.wantUpper, tt.wantMiddle, tt.wantLower)
+			}
+		})
+	}
+}
+
+func TestTrim(t *testing.T) {
+	line := SMA([]float64{1, 2, 3}, 1)
+	tests := []struct {
+		name string
+		n    int
+		want int
+	}{
+		{name: "none", n: 0, want: 3},
+		{name: "some", n: 2, want: 1},
+		{name: "more than the line", n: 5, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Result{"value": line}.Trim(tt.n)
+			if len(got["value"]) != tt.want {
+				t.Errorf("Trim(%d) kept %d rows, want %d", tt.n, len(got["value"]), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package predictor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	// step is one call against the breaker, expire moves past the cooldown first
+	type step struct {
+		expire bool
+		call   string // "success", "failure" or "release", after allow
+		allow  bool
+	}
+
+	tests := []struct {
+		name      string
+		threshold int
+		steps     []step
+	}{
+		{
+			name:      "stays closed below the threshold",
+			threshold: 3,
+			steps: []step{
+				{call: "failure", allow: true},
+				{call: "failure", allow: true},
+				{call: "success", allow: true},
+				{call: "failure", allow: true},
+				{call: "failure", allow: true},
+				{allow: true},
+			},
+		},
+		{
+			name:      "opens at the threshold",
+			threshold: 2,
+			steps: []step{
+				{call: "failure", allow: true},
+				{call: "failure", allow: true},
+				{allow: false},
+			},
+		},
+		{
+			name:      "half open lets a single probe through",
+			threshold: 1,
+			steps: []step{
+				{call: "failure", allow: true},
+				{expire: true, allow: true},
+				{allow: false},
+			},
+		},
+		{
+			name:      "successful probe closes",
+			threshold: 1,
+			steps: []step{
+				{call: "failure", allow: true},
+				{expire: true, call: "success", allow: true},
+				{allow: true},
+				{allow: true},
+			},
+		},
+		{
+			name:      "failed probe opens again",
+			threshold: 1,
+			steps: []step{
+				{call: "failure", allow: true},
+				{expire: true, call: "failure", allow: true},
+				{allow: false},
+			},
+		},
+		{
+			name:      "released probe lets another probe through",
+			threshold: 1,
+			steps: []step{
+				{call: "failure", allow: true},
+				{expire: true, call: "release", allow: true},
+				{call: "success", allow: true},
+				{allow: true},
+			},
+		},
+		{
+			name:      "threshold zero never opens",
+			threshold: 0,
+			steps: []step{
+				{call: "failure", allow: true},
+				{call: "failure", allow: true},
+				{allow: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBreaker(tt.threshold, time.Hour)
+			for i, s := range tt.steps {
+				if s.expire {
+					b.openUntil = time.Now().Add(-time.Second)
+				}
+				if got := b.allow(); got != s.allow {
+					t.Fatalf("step %d: allow() = %v, want %v", i, got, s.allow)
+				}
+				switch s.call {
+				case "success":
+					b.success()
+				case "failure":
+					b.failure()
+				case "release":
+					b.release()
+				}
+			}
+		})
+	}
+}
+
+func TestBreakerRemaining(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		wantOpen bool
+	}{
+		{name: "closed", failures: 1, wantOpen: false},
+		{name: "open", failures: 2, wantOpen: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBreaker(2, time.Minute)
+			for range tt.failures {
+				b.failure()
+			}
+			got := b.remaining()
+			if tt.wantOpen && (got <= 0 || got > time.Minute) {
+				t.Errorf("remaining() = %v, want between 0 and %v", got, time.Minute)
+			}
+			if !tt.wantOpen && got != 0 {
+				t.Errorf("remaining() = %v, want 0", got)
+			}
+		})
+	}
+}
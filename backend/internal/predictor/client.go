@@ -0,0 +1,172 @@
+package predictor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrUnavailable is returned while the circuit breaker is open or when the predictor could not be reached
+var ErrUnavailable = errors.New("predictor unavailable")
+
+// StatusError is a non 200 reply that is not worth retrying, such as a 4xx. Body is its error message
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("predictor returned %d: %s", e.StatusCode, e.Body)
+}
+
+type Config struct {
+	// BaseURL is where the FastAPI predictor listens, e.g. http://localhost:8000
+	BaseURL string
+	// Timeout bounds a single attempt and CallTimeout the whole call including retries and backoff,
+	// the caller's context can end it earlier
+	Timeout     time.Duration
+	CallTimeout time.Duration
+	// MaxRetries is how many times a connection error or 5xx reply is retried
+	MaxRetries int
+	// Backoff is the base delay before the first retry, it doubles for every further retry
+	Backoff time.Duration
+	// FailureThreshold consecutive failures open the breaker for Cooldown
+	FailureThreshold int
+	Cooldown         time.Duration
+}
+
+type Client struct {
+	cfg     Config
+	http    *http.Client
+	breaker *breaker
+}
+
+func New(cfg Config) *Client {
+	return &Client{
+		cfg:     cfg,
+		http:    &http.Client{},
+		breaker: newBreaker(cfg.FailureThreshold, cfg.Cooldown),
+	}
+}
+
+// Predict posts payload to /api/predict and decodes the reply into out.
+// Connection errors and 5xx replies are retried with jittered backoff. Once the retries or CallTimeout
+// are used up, or the reply cannot be decoded, ErrUnavailable is returned. 4xx replies are returned as
+// *StatusError. The whole call counts once for the circuit breaker: a decoded reply as a success, a
+// 4xx or a caller that gave up as neither, and anything else as a failure.
+func (c *Client) Predict(ctx context.Context, payload any, out any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	if !c.breaker.allow() {
+		return ErrUnavailable
+	}
+
+	callCtx := ctx
+	if c.cfg.CallTimeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, c.cfg.CallTimeout)
+		defer cancel()
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleep(callCtx, c.backoff(attempt)); err != nil {
+				lastErr = err
+				break
+			}
+		}
+
+		retry, err := c.do(callCtx, body, out)
+		if err == nil {
+			c.breaker.success()
+			return nil
+		}
+		lastErr = err
+
+		var statusErr *StatusError
+		if !retry && errors.As(err, &statusErr) {
+			c.breaker.release()
+			return err
+		}
+		if !retry || callCtx.Err() != nil {
+			break
+		}
+	}
+
+	// the caller gave up, that says nothing about the predictor's health
+	if ctx.Err() != nil {
+		c.breaker.release()
+		return ctx.Err()
+	}
+	c.breaker.failure()
+	return fmt.Errorf("%w: %v", ErrUnavailable, lastErr)
+}
+
+// RetryAfter is how long the circuit breaker stays open, 0 when it is closed or probing
+func (c *Client) RetryAfter() time.Duration {
+	return c.breaker.remaining()
+}
+
+// do makes one attempt and reports whether a failure is worth retrying
+func (c *Client) do(ctx context.Context, body []byte, out any) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	url := strings.TrimRight(c.cfg.BaseURL, "/") + "/api/predict"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		message, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return true, err
+		}
+		return resp.StatusCode >= 500, &StatusError{StatusCode: resp.StatusCode, Body: string(message)}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// backoff returns a random delay between half and all of Backoff * 2^(attempt-1)
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.cfg.Backoff << (attempt - 1)
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
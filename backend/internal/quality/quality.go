@@ -0,0 +1,203 @@
+package quality
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"stockcast/internal/calendar"
+	"stockcast/internal/store"
+)
+
+type Options struct {
+	// JumpThreshold is the relative day over day close change reported as suspicious, 0.25 means 25%
+	JumpThreshold float64
+	// StaleDays is how many trading days a code may lag behind the market before it counts as stopped
+	StaleDays int
+}
+
+// Issue is a single problem found on a date
+type Issue struct {
+	Date   time.Time `json:"date"`
+	Detail string    `json:"detail"`
+}
+
+type CodeReport struct {
+	TradingCode string      `json:"tradingCode"`
+	FirstDate   time.Time   `json:"firstDate"`
+	LastDate    time.Time   `json:"lastDate"`
+	Rows        int         `json:"rows"`
+	MissingDays []time.Time `json:"missingDays,omitempty"`
+	Duplicates  []time.Time `json:"duplicates,omitempty"`
+	BadPrices   []Issue     `json:"badPrices,omitempty"`
+	Jumps       []Issue     `json:"jumps,omitempty"`
+	Stale       bool        `json:"stale"`
+}
+
+func (c *CodeReport) hasIssues() bool {
+	return len(c.MissingDays) > 0 || len(c.Duplicates) > 0 || len(c.BadPrices) > 0 || len(c.Jumps) > 0 || c.Stale
+}
+
+type Totals struct {
+	Codes          int `json:"codes"`
+	CodesAffected  int `json:"codesAffected"`
+	MissingDays    int `json:"missingDays"`
+	Duplicates     int `json:"duplicates"`
+	BadPrices      int `json:"badPrices"`
+	Jumps          int `json:"jumps"`
+	StaleCodes     int `json:"staleCodes"`
+	MissingSession int `json:"missingSessions"`
+}
+
+type Report struct {
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	TradingDays int       `json:"tradingDays"`
+	// MarketLastDate is the latest date any code has data for
+	MarketLastDate *time.Time `json:"marketLastDate"`
+	// MissingSessions are calendar trading days without a single row, usually a failed import
+	MissingSessions []time.Time   `json:"missingSessions"`
+	Totals          Totals        `json:"totals"`
+	Codes           []*CodeReport `json:"codes"`
+}
+
+// Builder collects rows ordered by trading code and date and turns them into a Report
+type Builder struct {
+	cal        *calendar.Calendar
+	start, end time.Time
+	opts       Options
+
+	marketDays map[time.Time]bool
+	codes      []*codeState
+	current    *codeState
+}
+
+type codeState struct {
+	report   *CodeReport
+	dates    map[time.Time]bool
+	lastDate time.Time
+	prev     *store.Stock
+}
+
+func NewBuilder(cal *calendar.Calendar, start, end time.Time, opts Options) *Builder {
+	return &Builder{
+		cal:        cal,
+		start:      calendar.Date(start),
+		end:        calendar.Date(end),
+		opts:       opts,
+		marketDays: map[time.Time]bool{},
+	}
+}
+
+// Add checks one row, rows must arrive ordered by trading code, date and id
+func (b *Builder) Add(stock *store.Stock) error {
+	date := calendar.Date(stock.Date)
+	b.marketDays[date] = true
+
+	if b.current == nil || b.current.report.TradingCode != stock.TradingCode {
+		b.current = &codeState{
+			report: &CodeReport{TradingCode: stock.TradingCode, FirstDate: date},
+			dates:  map[time.Time]bool{},
+		}
+		b.codes = append(b.codes, b.current)
+	}
+	c := b.current
+	c.report.Rows++
+	c.report.LastDate = date
+
+	if c.dates[date] {
+		c.report.Duplicates = append(c.report.Duplicates, date)
+		return nil
+	}
+	c.dates[date] = true
+
+	if detail := badPrice(stock); detail != "" {
+		c.report.BadPrices = append(c.report.BadPrices, Issue{Date: date, Detail: detail})
+	}
+	if c.prev != nil && c.prev.Closep > 0 && stock.Closep > 0 {
+		change := stock.Closep/c.prev.Closep - 1
+		if math.Abs(change) > b.opts.JumpThreshold {
+			c.report.Jumps = append(c.report.Jumps, Issue{
+				Date:   date,
+				Detail: fmt.Sprintf("close moved %.1f%% from %v to %v", change*100, c.prev.Closep, stock.Closep),
+			})
+		}
+	}
+	c.prev = stock
+	return nil
+}
+
+// badPrice describes zero or negative prices, the day range is only checked for rows that traded
+func badPrice(stock *store.Stock) string {
+	switch {
+	case stock.Closep <= 0:
+		return fmt.Sprintf("closep is %v", stock.Closep)
+	case stock.Ycp < 0:
+		return fmt.Sprintf("ycp is %v", stock.Ycp)
+	case stock.Volume > 0 && (stock.Ltp <= 0 || stock.High <= 0 || stock.Low <= 0 || stock.Openp <= 0):
+		return fmt.Sprintf("traded with ltp=%v high=%v low=%v openp=%v", stock.Ltp, stock.High, stock.Low, stock.Openp)
+	}
+	return ""
+}
+
+// Report finishes the checks that need every row, gaps against the calendar and stale codes
+func (b *Builder) Report() *Report {
+	report := &Report{
+		Start:       b.start,
+		End:         b.end,
+		TradingDays: b.cal.TradingDaysBetween(b.start, b.end),
+		Codes:       []*CodeReport{},
+	}
+
+	var sessions []time.Time
+	for date := b.start; !date.After(b.end); date = date.AddDate(0, 0, 1) {
+		if !b.cal.IsTradingDay(date) {
+			continue
+		}
+		if b.marketDays[date] {
+			sessions = append(sessions, date)
+		} else {
+			report.MissingSessions = append(report.MissingSessions, date)
+		}
+	}
+	if len(sessions) > 0 {
+		last := sessions[len(sessions)-1]
+		report.MarketLastDate = &last
+	}
+
+	for _, c := range b.codes {
+		// a code is only expected on market sessions while it is listed, from its first to its last row
+		for _, session := range sessions {
+			if session.Before(c.report.FirstDate) || session.After(c.report.LastDate) {
+				continue
+			}
+			if !c.dates[session] {
+				c.report.MissingDays = append(c.report.MissingDays, session)
+			}
+		}
+		if report.MarketLastDate != nil {
+			lag := b.cal.TradingDaysBetween(c.report.LastDate, *report.MarketLastDate) - 1
+			c.report.Stale = lag > b.opts.StaleDays
+		}
+
+		report.Totals.Codes++
+		report.Totals.MissingDays += len(c.report.MissingDays)
+		report.Totals.Duplicates += len(c.report.Duplicates)
+		report.Totals.BadPrices += len(c.report.BadPrices)
+		report.Totals.Jumps += len(c.report.Jumps)
+		if c.report.Stale {
+			report.Totals.StaleCodes++
+		}
+		if c.report.hasIssues() {
+			report.Totals.CodesAffected++
+			report.Codes = append(report.Codes, c.report)
+		}
+	}
+	report.Totals.MissingSession = len(report.MissingSessions)
+
+	sort.Slice(report.Codes, func(i, j int) bool {
+		return report.Codes[i].TradingCode < report.Codes[j].TradingCode
+	})
+	return report
+}
@@ -0,0 +1,133 @@
+package quality
+
+import (
+	"testing"
+	"time"
+
+	"stockcast/internal/calendar"
+	"stockcast/internal/store"
+)
+
+// 2024-01-01 is a Monday, DSE weekends are Friday and Saturday
+func day(d int) time.Time {
+	return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC)
+}
+
+func row(code string, d int, closep float64) *store.Stock {
+	return &store.Stock{
+		TradingCode: code,
+		Date:        day(d),
+		Ltp:         closep,
+		High:        closep,
+		Low:         closep,
+		Openp:       closep,
+		Closep:      closep,
+		Ycp:         closep,
+		Volume:      100,
+	}
+}
+
+func TestBuilder(t *testing.T) {
+	opts := Options{JumpThreshold: 0.25, StaleDays: 1}
+
+	tests := []struct {
+		name string
+		rows []*store.Stock
+		// check inspects the single code report, nil when the code should have no issues
+		check func(t *testing.T, c *CodeReport)
+	}{
+		{
+			name: "clean",
+			rows: []*store.Stock{row("A", 1, 10), row("A", 2, 10), row("A", 3, 10), row("A", 4, 10)},
+		},
+		{
+			name: "missing day",
+			rows: []*store.Stock{row("A", 1, 10), row("A", 3, 10), row("A", 4, 10), row("B", 2, 10), row("B", 3, 10), row("B", 4, 10)},
+			check: func(t *testing.T, c *CodeReport) {
+				if len(c.MissingDays) != 1 || !c.MissingDays[0].Equal(day(2)) {
+					t.Errorf("MissingDays = %v, want [%s]", c.MissingDays, day(2).Format("2006-01-02"))
+				}
+			},
+		},
+		{
+			name: "duplicate",
+			rows: []*store.Stock{row("A", 1, 10), row("A", 1, 10), row("A", 2, 10), row("A", 3, 10), row("A", 4, 10)},
+			check: func(t *testing.T, c *CodeReport) {
+				if len(c.Duplicates) != 1 {
+					t.Errorf("Duplicates = %v, want one", c.Duplicates)
+				}
+			},
+		},
+		{
+			name: "bad price",
+			rows: []*store.Stock{row("A", 1, 10), row("A", 2, 0), row("A", 3, 10), row("A", 4, 10)},
+			check: func(t *testing.T, c *CodeReport) {
+				if len(c.BadPrices) != 1 || !c.BadPrices[0].Date.Equal(day(2)) {
+					t.Errorf("BadPrices = %v, want one on %s", c.BadPrices, day(2).Format("2006-01-02"))
+				}
+			},
+		},
+		{
+			name: "jump",
+			rows: []*store.Stock{row("A", 1, 10), row("A", 2, 10), row("A", 3, 15), row("A", 4, 15)},
+			check: func(t *testing.T, c *CodeReport) {
+				if len(c.Jumps) != 1 || !c.Jumps[0].Date.Equal(day(3)) {
+					t.Errorf("Jumps = %v, want one on %s", c.Jumps, day(3).Format("2006-01-02"))
+				}
+			},
+		},
+		{
+			name: "stale",
+			rows: []*store.Stock{row("A", 1, 10), row("B", 1, 10), row("B", 2, 10), row("B", 3, 10), row("B", 4, 10)},
+			check: func(t *testing.T, c *CodeReport) {
+				if !c.Stale {
+					t.Errorf("code is not stale after missing three sessions")
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBuilder(calendar.New(nil), day(1), day(4), opts)
+			for _, r := range tt.rows {
+				if err := b.Add(r); err != nil {
+					t.Fatalf("Add: %v", err)
+				}
+			}
+			report := b.Report()
+
+			if tt.check == nil {
+				if len(report.Codes) != 0 {
+					t.Fatalf("report has issues: %+v", report.Codes[0])
+				}
+				return
+			}
+			if len(report.Codes) != 1 || report.Codes[0].TradingCode != "A" {
+				t.Fatalf("report has %d codes with issues, want only A", len(report.Codes))
+			}
+			tt.check(t, report.Codes[0])
+		})
+	}
+}
+
+func TestReportMissingSessions(t *testing.T) {
+	// Thursday the 4th to Monday the 8th, the weekend in between is not a session
+	b := NewBuilder(calendar.New(nil), day(4), day(8), Options{JumpThreshold: 0.25, StaleDays: 5})
+	if err := b.Add(row("A", 4, 10)); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	report := b.Report()
+
+	if report.TradingDays != 3 {
+		t.Errorf("TradingDays = %d, want 3", report.TradingDays)
+	}
+	want := []time.Time{day(7), day(8)}
+	if len(report.MissingSessions) != len(want) {
+		t.Fatalf("MissingSessions = %v, want %v", report.MissingSessions, want)
+	}
+	for i := range want {
+		if !report.MissingSessions[i].Equal(want[i]) {
+			t.Errorf("MissingSessions[%d] = %s, want %s", i, report.MissingSessions[i].Format("2006-01-02"), want[i].Format("2006-01-02"))
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// CalendarDay is an exception to the regular DSE week, a holiday or a special session
+type CalendarDay struct {
+	Date         time.Time `json:"date"`
+	IsTradingDay bool      `json:"isTradingDay"`
+	Description  string    `json:"description"`
+}
+
+type CalendarStore struct {
+	db *sql.DB
+}
+
+func (s *CalendarStore) Get(ctx context.Context) ([]*CalendarDay, error) {
+	query := `SELECT date, is_trading_day, description
+              FROM trading_calendar
+              ORDER BY date ASC`
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []*CalendarDay
+	for rows.Next() {
+		var day CalendarDay
+		err := rows.Scan(
+			&day.Date,
+			&day.IsTradingDay,
+			&day.Description,
+		)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, &day)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return days, nil
+}
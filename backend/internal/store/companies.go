@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type Company struct {
+	TradingCode string     `json:"tradingCode"`
+	Name        string     `json:"name"`
+	Sector      string     `json:"sector"`
+	Category    string     `json:"category"`
+	ListingDate *time.Time `json:"listingDate,omitempty"`
+	FaceValue   *float64   `json:"faceValue,omitempty"`
+	TotalShares *int64     `json:"totalShares,omitempty"`
+}
+
+type CompanyStore struct {
+	db *sql.DB
+}
+
+// Get returns every instrument, optionally narrowed down to a sector and/or category
+func (s *CompanyStore) Get(ctx context.Context, sector string, category string) ([]*Company, error) {
+	query := `SELECT trading_code, company_name, sector, category, listing_date, face_value, total_shares
+              FROM instruments
+              WHERE ($1 = '' OR sector = $1) AND ($2 = '' OR category = $2)
+              ORDER BY trading_code ASC`
+	rows, err := s.db.QueryContext(ctx, query, sector, category)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var companies []*Company
+	for rows.Next() {
+		var company Company
+		err := rows.Scan(
+			&company.TradingCode,
+			&company.Name,
+			&company.Sector,
+			&company.Category,
+			&company.ListingDate,
+			&company.FaceValue,
+			&company.TotalShares,
+		)
+		if err != nil {
+			return nil, err
+		}
+		companies = append(companies, &company)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return companies, nil
+}
+
+func (s *CompanyStore) GetByCode(ctx context.Context, tradingCode string) (*Company, error) {
+	query := `SELECT trading_code, company_name, sector, category, listing_date, face_value, total_shares
+              FROM instruments
+              WHERE trading_code = $1`
+	company := &Company{}
+	err := s.db.QueryRowContext(ctx, query, tradingCode).Scan(
+		&company.TradingCode,
+		&company.Name,
+		&company.Sector,
+		&company.Category,
+		&company.ListingDate,
+		&company.FaceValue,
+		&company.TotalShares,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+	return company, nil
+}
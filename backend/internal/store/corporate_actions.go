@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	ActionBonus  = "bonus"
+	ActionRights = "rights"
+	ActionSplit  = "split"
+	ActionCash   = "cash"
+)
+
+type CorporateAction struct {
+	ID           int64     `json:"id"`
+	TradingCode  string    `json:"tradingCode"`
+	RecordDate   time.Time `json:"recordDate"`
+	Type         string    `json:"type"`
+	Ratio        float64   `json:"ratio"`
+	IssuePrice   *float64  `json:"issuePrice,omitempty"`
+	CashDividend float64   `json:"cashDividend"`
+}
+
+type CorporateActionStore struct {
+	db *sql.DB
+}
+
+func (s *CorporateActionStore) GetByCode(ctx context.Context, tradingCode string) ([]*CorporateAction, error) {
+	query := `SELECT id, trading_code, record_date, action_type, ratio, issue_price, cash_dividend
+              FROM corporate_actions
+              WHERE trading_code = $1
+              ORDER BY record_date ASC`
+	rows, err := s.db.QueryContext(ctx, query, tradingCode)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var actions []*CorporateAction
+	for rows.Next() {
+		var action CorporateAction
+		err := rows.Scan(
+			&action.ID,
+			&action.TradingCode,
+			&action.RecordDate,
+			&action.Type,
+			&action.Ratio,
+			&action.IssuePrice,
+			&action.CashDividend,
+		)
+		if err != nil {
+			return nil, err
+		}
+		actions = append(actions, &action)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
+
+// GetByCodes loads the actions of several trading codes with one query, keyed by trading code
+func (s *CorporateActionStore) GetByCodes(ctx context.Context, tradingCodes []string) (map[string][]*CorporateAction, error) {
+	query := `SELECT id, trading_code, record_date, action_type, ratio, issue_price, cash_dividend
+              FROM corporate_actions
+              WHERE trading_code = ANY($1)
+              ORDER BY trading_code ASC, record_date ASC`
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(tradingCodes))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	actions := make(map[string][]*CorporateAction, len(tradingCodes))
+	for rows.Next() {
+		var action CorporateAction
+		err := rows.Scan(
+			&action.ID,
+			&action.TradingCode,
+			&action.RecordDate,
+			&action.Type,
+			&action.Ratio,
+			&action.IssuePrice,
+			&action.CashDividend,
+		)
+		if err != nil {
+			return nil, err
+		}
+		actions[action.TradingCode] = append(actions[action.TradingCode], &action)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// stockSortColumns maps the JSON field names that can be sorted on to their columns
+var stockSortColumns = map[string]string{
+	"date":        "date",
+	"tradingCode": "trading_code",
+	"ltp":         "ltp",
+	"high":        "high",
+	"low":         "low",
+	"openp":       "openp",
+	"closep":      "closep",
+	"ycp":         "ycp",
+	"trade":       "trade",
+	"value":       "value",
+	"volume":      "volume",
+}
+
+// StockFilter narrows down, sorts and paginates the stocks of a trading day.
+// Sort entries are JSON field names, prefixed with "-" for descending order.
+// A PageSize of 0 returns every matching row.
+type StockFilter struct {
+	Codes     []string
+	MinLtp    *float64
+	MaxLtp    *float64
+	MinVolume *int
+	MaxVolume *int
+	Sort      []string
+	Page      int `validate:"gte=1,lte=10000000"`
+	PageSize  int `validate:"omitempty,gte=1,lte=1000"`
+}
+
+// ValidateSort reports the first sort entry that is not a sortable field
+func (f StockFilter) ValidateSort() error {
+	for _, s := range f.Sort {
+		if _, ok := stockSortColumns[strings.TrimPrefix(s, "-")]; !ok {
+			return fmt.Errorf("invalid sort field %q", s)
+		}
+	}
+	return nil
+}
+
+func (f StockFilter) orderBy() string {
+	clauses := make([]string, 0, len(f.Sort)+1)
+	for _, s := range f.Sort {
+		column, ok := stockSortColumns[strings.TrimPrefix(s, "-")]
+		if !ok {
+			continue
+		}
+		if strings.HasPrefix(s, "-") {
+			clauses = append(clauses, column+" DESC")
+		} else {
+			clauses = append(clauses, column+" ASC")
+		}
+	}
+	// keeps pages stable when the sort fields tie
+	clauses = append(clauses, "trading_code ASC")
+	return strings.Join(clauses, ", ")
+}
+
+// limit returns nil for an unpaginated filter, LIMIT NULL is no limit in postgres
+func (f StockFilter) limit() *int {
+	if f.PageSize == 0 {
+		return nil
+	}
+	return &f.PageSize
+}
+
+func (f StockFilter) offset() int {
+	return (f.Page - 1) * f.PageSize
+}
+
+type Metadata struct {
+	CurrentPage  int `json:"currentPage,omitempty"`
+	PageSize     int `json:"pageSize,omitempty"`
+	FirstPage    int `json:"firstPage,omitempty"`
+	LastPage     int `json:"lastPage,omitempty"`
+	TotalRecords int `json:"totalRecords"`
+}
+
+func calculateMetadata(totalRecords, page, pageSize int) Metadata {
+	if totalRecords == 0 {
+		return Metadata{}
+	}
+	// unpaginated, everything is on the one page
+	if pageSize == 0 {
+		return Metadata{
+			CurrentPage:  1,
+			PageSize:     totalRecords,
+			FirstPage:    1,
+			LastPage:     1,
+			TotalRecords: totalRecords,
+		}
+	}
+
+	return Metadata{
+		CurrentPage:  page,
+		PageSize:     pageSize,
+		FirstPage:    1,
+		LastPage:     int(math.Ceil(float64(totalRecords) / float64(pageSize))),
+		TotalRecords: totalRecords,
+	}
+}
@@ -0,0 +1,158 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Mover is a stock row ranked by its day change or trading activity
+type Mover struct {
+	Stock
+	Change        float64 `json:"change"`
+	ChangePercent float64 `json:"changePercent"`
+}
+
+// MarketSummary is the market breadth and activity of a single trading day
+type MarketSummary struct {
+	Date        time.Time `json:"date"`
+	Advancers   int       `json:"advancers"`
+	Decliners   int       `json:"decliners"`
+	Unchanged   int       `json:"unchanged"`
+	TotalValue  float64   `json:"totalValue"`
+	TotalVolume int64     `json:"totalVolume"`
+	TotalTrades int64     `json:"totalTrades"`
+	ListedCodes int       `json:"listedCodes"`
+	TradedCodes int       `json:"tradedCodes"`
+}
+
+// moverOrders maps the ranking criteria to safe ORDER BY clauses
+var moverOrders = map[string]string{
+	"gain":   "change_percent DESC",
+	"loss":   "change_percent ASC",
+	"value":  "value DESC",
+	"volume": "volume DESC",
+	"trade":  "trade DESC",
+}
+
+type MarketStore struct {
+	db *sql.DB
+}
+
+// Movers ranks the stocks of a trading day by the given criteria, the latest trading day is used when date is nil.
+// The day change is ltp against ycp, codes without a previous close are left out of gain/loss rankings.
+func (s *MarketStore) Movers(ctx context.Context, date *time.Time, by string, limit int) ([]*Mover, error) {
+	order, ok := moverOrders[by]
+	if !ok {
+		order = moverOrders["gain"]
+	}
+	excludeNoYcp := by == "gain" || by == "loss"
+
+	query := `SELECT id, date, trading_code, ltp, high, low, openp, closep, ycp, trade, value, volume,
+                     ltp - ycp AS change,
+                     CASE WHEN ycp > 0 THEN (ltp - ycp) / ycp * 100 ELSE 0 END AS change_percent
+              FROM stock_history
+              WHERE date = COALESCE($1::date, (SELECT MAX(date) FROM stock_history))
+                AND (NOT $2 OR ycp > 0)
+              ORDER BY ` + order + `, trading_code ASC
+              LIMIT $3`
+	rows, err := s.db.QueryContext(ctx, query, date, excludeNoYcp, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var movers []*Mover
+	for rows.Next() {
+		var mover Mover
+		err := rows.Scan(
+			&mover.ID,
+			&mover.Date,
+			&mover.TradingCode,
+			&mover.Ltp,
+			&mover.High,
+			&mover.Low,
+			&mover.Openp,
+			&mover.Closep,
+			&mover.Ycp,
+			&mover.Trade,
+			&mover.Value,
+			&mover.Volume,
+			&mover.Change,
+			&mover.ChangePercent,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movers = append(movers, &mover)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return movers, nil
+}
+
+// GetSummary returns the precomputed summary of a trading day, the latest trading day is used when date is nil
+func (s *MarketStore) GetSummary(ctx context.Context, date *time.Time) (*MarketSummary, error) {
+	query := `SELECT date, advancers, decliners, unchanged, total_value, total_volume, total_trades, listed_codes, traded_codes
+              FROM market_summaries
+              WHERE date = COALESCE($1::date, (SELECT MAX(date) FROM market_summaries))`
+	summary := &MarketSummary{}
+	err := s.db.QueryRowContext(ctx, query, date).Scan(
+		&summary.Date,
+		&summary.Advancers,
+		&summary.Decliners,
+		&summary.Unchanged,
+		&summary.TotalValue,
+		&summary.TotalVolume,
+		&summary.TotalTrades,
+		&summary.ListedCodes,
+		&summary.TradedCodes,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+	return summary, nil
+}
+
+func (s *MarketStore) GetSummaryHistory(ctx context.Context, start time.Time, end time.Time) ([]*MarketSummary, error) {
+	query := `SELECT date, advancers, decliners, unchanged, total_value, total_volume, total_trades, listed_codes, traded_codes
+              FROM market_summaries
+              WHERE date >= $1 AND date <= $2
+              ORDER BY date ASC`
+	rows, err := s.db.QueryContext(ctx, query, start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []*MarketSummary
+	for rows.Next() {
+		var summary MarketSummary
+		err := rows.Scan(
+			&summary.Date,
+			&summary.Advancers,
+			&summary.Decliners,
+			&summary.Unchanged,
+			&summary.TotalValue,
+			&summary.TotalVolume,
+			&summary.TotalTrades,
+			&summary.ListedCodes,
+			&summary.TradedCodes,
+		)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, &summary)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return summaries, nil
+}
@@ -0,0 +1,166 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// PredictionJob is an asynchronous batch prediction, Done counts finished items out of Total.
+// Attempts identifies the current claim, see Claim and Update.
+type PredictionJob struct {
+	ID          int64           `json:"id"`
+	Status      string          `json:"status"`
+	Request     json.RawMessage `json:"request"`
+	Total       int             `json:"total"`
+	Done        int             `json:"done"`
+	Result      json.RawMessage `json:"result"`
+	Error       string          `json:"error,omitempty"`
+	Attempts    int             `json:"attempts"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	StartedAt   *time.Time      `json:"startedAt"`
+	HeartbeatAt *time.Time      `json:"heartbeatAt"`
+	FinishedAt  *time.Time      `json:"finishedAt"`
+}
+
+type PredictionJobStore struct {
+	db *sql.DB
+}
+
+func (s *PredictionJobStore) Create(ctx context.Context, job *PredictionJob) error {
+	query := `INSERT INTO prediction_jobs (status, request, total)
+              VALUES ($1, $2, $3)
+              RETURNING id, created_at`
+	return s.db.QueryRowContext(ctx, query, job.Status, []byte(job.Request), job.Total).Scan(&job.ID, &job.CreatedAt)
+}
+
+func (s *PredictionJobStore) GetByID(ctx context.Context, id int64) (*PredictionJob, error) {
+	query := `SELECT id, status, request, total, done, result, error, attempts, created_at, started_at, heartbeat_at, finished_at
+              FROM prediction_jobs
+              WHERE id = $1`
+	job, err := scanPredictionJob(s.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+	return job, nil
+}
+
+// Claim marks the oldest queued job, or a running job whose heartbeat is older than lease, as running
+// and returns it. SKIP LOCKED lets several processes claim concurrently without taking the same job.
+// A job that has already been claimed maxAttempts times is marked failed instead of being run again,
+// so a job that keeps killing its runner cannot loop forever.
+// Returns ErrorNotFound when there is nothing to run.
+func (s *PredictionJobStore) Claim(ctx context.Context, lease time.Duration, maxAttempts int) (*PredictionJob, error) {
+	exhausted := `UPDATE prediction_jobs
+              SET status = 'failed', error = 'the job was abandoned after too many attempts', finished_at = NOW()
+              WHERE attempts >= $2
+                AND (status = 'queued'
+                     OR (status = 'running' AND (heartbeat_at IS NULL OR heartbeat_at < NOW() - $1 * INTERVAL '1 second')))`
+	if _, err := s.db.ExecContext(ctx, exhausted, lease.Seconds(), maxAttempts); err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE prediction_jobs
+              SET status = 'running', done = 0, started_at = NOW(), heartbeat_at = NOW(), attempts = attempts + 1
+              WHERE id = (
+                  SELECT id FROM prediction_jobs
+                  WHERE (status = 'queued'
+                         OR (status = 'running' AND (heartbeat_at IS NULL OR heartbeat_at < NOW() - $1 * INTERVAL '1 second')))
+                    AND attempts < $2
+                  ORDER BY id ASC
+                  FOR UPDATE SKIP LOCKED
+                  LIMIT 1
+              )
+              RETURNING id, status, request, total, done, result, error, attempts, created_at, started_at, heartbeat_at, finished_at`
+	job, err := scanPredictionJob(s.db.QueryRowContext(ctx, query, lease.Seconds(), maxAttempts))
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+	return job, nil
+}
+
+// Update saves the job's status, progress, result and finish time and renews its heartbeat.
+// Returns ErrorNotFound when the claim was lost, the job was claimed again or is no longer running.
+func (s *PredictionJobStore) Update(ctx context.Context, job *PredictionJob) error {
+	query := `UPDATE prediction_jobs
+              SET status = $3, total = $4, done = $5, result = $6, error = $7, finished_at = $8, heartbeat_at = NOW()
+              WHERE id = $1 AND attempts = $2 AND status = 'running'`
+	// a nil interface is sent as NULL, an empty []byte would not be valid JSONB
+	var result any
+	if job.Result != nil {
+		result = []byte(job.Result)
+	}
+	res, err := s.db.ExecContext(ctx, query, job.ID, job.Attempts, job.Status, job.Total, job.Done, result, job.Error, job.FinishedAt)
+	if err != nil {
+		return err
+	}
+	return expectRow(res)
+}
+
+// Requeue hands a running job back to the queue, used when a job is interrupted by a shutdown.
+// The interrupted claim is not counted against the job's attempts.
+func (s *PredictionJobStore) Requeue(ctx context.Context, job *PredictionJob) error {
+	query := `UPDATE prediction_jobs
+              SET status = 'queued', done = 0, started_at = NULL, heartbeat_at = NULL, attempts = attempts - 1
+              WHERE id = $1 AND attempts = $2 AND status = 'running'`
+	res, err := s.db.ExecContext(ctx, query, job.ID, job.Attempts)
+	if err != nil {
+		return err
+	}
+	return expectRow(res)
+}
+
+func expectRow(res sql.Result) error {
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrorNotFound
+	}
+	return nil
+}
+
+func scanPredictionJob(row interface{ Scan(...any) error }) (*PredictionJob, error) {
+	var job PredictionJob
+	var request, result []byte
+	err := row.Scan(
+		&job.ID,
+		&job.Status,
+		&request,
+		&job.Total,
+		&job.Done,
+		&result,
+		&job.Error,
+		&job.Attempts,
+		&job.CreatedAt,
+		&job.StartedAt,
+		&job.HeartbeatAt,
+		&job.FinishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	job.Request = request
+	job.Result = result
+	return &job, nil
+}
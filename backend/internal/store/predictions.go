@@ -3,7 +3,10 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type predictionStore struct {
@@ -48,3 +51,318 @@ func (s *predictionStore) GetHistory(ctx context.Context, tradingCode string, st
 	}
 	return stocks, nil
 }
+
+// GetHistoryBatch loads the history of several trading codes with one query, keyed by trading code.
+// Codes without rows in the range are missing from the map.
+func (s *predictionStore) GetHistoryBatch(ctx context.Context, tradingCodes []string, start time.Time, end time.Time) (map[string][]*Stock, error) {
+	query := `SELECT id, date, trading_code, ltp, high, low, openp, closep, ycp, trade, value, volume
+              FROM stock_history
+              WHERE trading_code = ANY($1) AND date >= $2 AND date <= $3
+              ORDER BY trading_code ASC, date ASC`
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(tradingCodes), start, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	histories := make(map[string][]*Stock, len(tradingCodes))
+	for rows.Next() {
+		var stock Stock
+		err := rows.Scan(
+			&stock.ID,
+			&stock.Date,
+			&stock.TradingCode,
+			&stock.Ltp,
+			&stock.High,
+			&stock.Low,
+			&stock.Openp,
+			&stock.Closep,
+			&stock.Ycp,
+			&stock.Trade,
+			&stock.Value,
+			&stock.Volume,
+		)
+		if err != nil {
+			return nil, err
+		}
+		histories[stock.TradingCode] = append(histories[stock.TradingCode], &stock)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return histories, nil
+}
+
+// Prediction is a stored forecast, Points holds one predicted close per trading day of the horizon
+type Prediction struct {
+	ID              int64     `json:"id"`
+	TradingCode     string    `json:"tradingCode"`
+	Horizon         int       `json:"horizon"`
+	RequestedAt     time.Time `json:"requestedAt"`
+	HistoryLastDate time.Time `json:"historyLastDate"`
+	LastClose       float64   `json:"lastClose"`
+	Adjusted        bool      `json:"adjusted"`
+	ModelVersion    string    `json:"modelVersion"`
+	DataPointsUsed  int       `json:"dataPointsUsed"`
+	// DataVersion identifies the inputs the forecast was made from, see the prediction cache
+	DataVersion string `json:"dataVersion"`
+	// Cached is set when the forecast was served from the prediction cache instead of the predictor
+	Cached bool              `json:"cached"`
+	Points []PredictionPoint `json:"points"`
+}
+
+type PredictionPoint struct {
+	Date  time.Time `json:"date"`
+	Price float64   `json:"price"`
+	// ActualPrice is the realized close, nil until the point has been scored
+	ActualPrice *float64 `json:"actualPrice"`
+}
+
+// Create stores the prediction with its points and fills in ID and RequestedAt
+func (s *predictionStore) Create(ctx context.Context, prediction *Prediction) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		query := `INSERT INTO predictions (trading_code, horizon, history_last_date, last_close, adjusted, model_version, data_points_used, data_version, cached)
+                  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+                  RETURNING id, requested_at`
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			prediction.TradingCode,
+			prediction.Horizon,
+			prediction.HistoryLastDate,
+			prediction.LastClose,
+			prediction.Adjusted,
+			prediction.ModelVersion,
+			prediction.DataPointsUsed,
+			prediction.DataVersion,
+			prediction.Cached,
+		).Scan(&prediction.ID, &prediction.RequestedAt)
+		if err != nil {
+			return err
+		}
+
+		pointQuery := `INSERT INTO prediction_points (prediction_id, date, price) VALUES ($1, $2, $3)`
+		for _, point := range prediction.Points {
+			if _, err := tx.ExecContext(ctx, pointQuery, prediction.ID, point.Date, point.Price); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// List returns predictions requested since the given time, newest first, optionally for one trading code
+func (s *predictionStore) List(ctx context.Context, tradingCode string, since time.Time, limit int) ([]*Prediction, error) {
+	query := `SELECT p.id, p.trading_code, p.horizon, p.requested_at, p.history_last_date, p.last_close,
+                     p.adjusted, p.model_version, p.data_points_used, p.data_version, p.cached, pp.date, pp.price, pp.actual_price
+              FROM (
+                  SELECT * FROM predictions
+                  WHERE ($1 = '' OR trading_code = $1) AND requested_at >= $2
+                  ORDER BY requested_at DESC, id DESC
+                  LIMIT $3
+              ) p
+              LEFT JOIN prediction_points pp ON pp.prediction_id = p.id
+              ORDER BY p.requested_at DESC, p.id DESC, pp.date ASC`
+	rows, err := s.db.QueryContext(ctx, query, tradingCode, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	predictions := []*Prediction{}
+	var current *Prediction
+	for rows.Next() {
+		var prediction Prediction
+		var date sql.NullTime
+		var price sql.NullFloat64
+		var actual sql.NullFloat64
+		err := rows.Scan(
+			&prediction.ID,
+			&prediction.TradingCode,
+			&prediction.Horizon,
+			&prediction.RequestedAt,
+			&prediction.HistoryLastDate,
+			&prediction.LastClose,
+			&prediction.Adjusted,
+			&prediction.ModelVersion,
+			&prediction.DataPointsUsed,
+			&prediction.DataVersion,
+			&prediction.Cached,
+			&date,
+			&price,
+			&actual,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil || current.ID != prediction.ID {
+			prediction.Points = []PredictionPoint{}
+			current = &prediction
+			predictions = append(predictions, current)
+		}
+		if date.Valid {
+			point := PredictionPoint{Date: date.Time, Price: price.Float64}
+			if actual.Valid {
+				point.ActualPrice = &actual.Float64
+			}
+			current.Points = append(current.Points, point)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return predictions, nil
+}
+
+// FindLatest returns the most recent stored prediction made from the same inputs, used as the
+// persistent prediction cache. Returns ErrorNotFound when the forecast has not been made yet.
+func (s *predictionStore) FindLatest(ctx context.Context, tradingCode string, horizon int, adjusted bool, dataVersion string) (*Prediction, error) {
+	query := `SELECT id, trading_code, horizon, requested_at, history_last_date, last_close,
+                     adjusted, model_version, data_points_used, data_version, cached
+              FROM predictions
+              WHERE trading_code = $1 AND horizon = $2 AND adjusted = $3 AND data_version = $4
+              ORDER BY requested_at DESC, id DESC
+              LIMIT 1`
+	var prediction Prediction
+	err := s.db.QueryRowContext(ctx, query, tradingCode, horizon, adjusted, dataVersion).Scan(
+		&prediction.ID,
+		&prediction.TradingCode,
+		&prediction.Horizon,
+		&prediction.RequestedAt,
+		&prediction.HistoryLastDate,
+		&prediction.LastClose,
+		&prediction.Adjusted,
+		&prediction.ModelVersion,
+		&prediction.DataPointsUsed,
+		&prediction.DataVersion,
+		&prediction.Cached,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrorNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	pointQuery := `SELECT date, price, actual_price
+                   FROM prediction_points
+                   WHERE prediction_id = $1
+                   ORDER BY date ASC`
+	rows, err := s.db.QueryContext(ctx, pointQuery, prediction.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prediction.Points = []PredictionPoint{}
+	for rows.Next() {
+		var point PredictionPoint
+		if err := rows.Scan(&point.Date, &point.Price, &point.ActualPrice); err != nil {
+			return nil, err
+		}
+		prediction.Points = append(prediction.Points, point)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &prediction, nil
+}
+
+// Score fills in the realized close for every unscored point whose date has a stock_history row,
+// and re-scores points whose close was corrected by a later import. Returns how many points changed.
+func (s *predictionStore) Score(ctx context.Context) (int64, error) {
+	query := `UPDATE prediction_points pp
+              SET actual_price = sh.closep, scored_at = NOW()
+              FROM predictions p, stock_history sh
+              WHERE pp.prediction_id = p.id
+                AND sh.trading_code = p.trading_code
+                AND sh.date = pp.date
+                AND (pp.scored_at IS NULL OR pp.actual_price IS DISTINCT FROM sh.closep)`
+	result, err := s.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+type AccuracyFilter struct {
+	TradingCode string    `validate:"max=50"`
+	Horizon     int       `validate:"omitempty,oneof=1 3 7"`
+	Start       time.Time `validate:"required"`
+	End         time.Time `validate:"required,gtefield=Start"`
+}
+
+// Accuracy summarizes scored points. MAPE is a percentage and HitRate is the share of points where the
+// predicted move from the last close had the same direction as the realized move. Points where the
+// close did not move have no direction and are left out of HitRate, which is nil when all were flat.
+// TradingCode is empty for the all codes row of a horizon.
+type Accuracy struct {
+	TradingCode string   `json:"tradingCode,omitempty"`
+	Horizon     int      `json:"horizon"`
+	Predictions int      `json:"predictions"`
+	Points      int      `json:"points"`
+	MAE         float64  `json:"mae"`
+	RMSE        float64  `json:"rmse"`
+	MAPE        *float64 `json:"mape"`
+	HitRate     *float64 `json:"hitRate"`
+}
+
+// Accuracy groups scored points whose date falls between start and end per trading code and horizon,
+// plus one row per horizon across all codes. Cached rows repeat a forecast that is already counted
+// and are left out.
+func (s *predictionStore) Accuracy(ctx context.Context, filter AccuracyFilter) ([]*Accuracy, error) {
+	query := `SELECT COALESCE(p.trading_code, ''), p.horizon,
+                     COUNT(DISTINCT p.id),
+                     COUNT(*),
+                     AVG(ABS(pp.price - pp.actual_price)),
+                     SQRT(AVG(POWER(pp.price - pp.actual_price, 2))),
+                     AVG(ABS(pp.price - pp.actual_price) / NULLIF(pp.actual_price, 0)) * 100,
+                     AVG(CASE WHEN SIGN(pp.price - p.last_close) = SIGN(pp.actual_price - p.last_close) THEN 1.0 ELSE 0.0 END)
+                         FILTER (WHERE pp.actual_price <> p.last_close)
+              FROM prediction_points pp
+              JOIN predictions p ON p.id = pp.prediction_id
+              WHERE pp.scored_at IS NOT NULL
+                AND NOT p.cached
+                AND ($1 = '' OR p.trading_code = $1)
+                AND ($2 = 0 OR p.horizon = $2)
+                AND pp.date >= $3 AND pp.date <= $4
+              GROUP BY GROUPING SETS ((p.trading_code, p.horizon), (p.horizon))
+              ORDER BY p.horizon ASC, GROUPING(p.trading_code) DESC, p.trading_code ASC`
+	rows, err := s.db.QueryContext(ctx, query, filter.TradingCode, filter.Horizon, filter.Start, filter.End)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*Accuracy{}
+	for rows.Next() {
+		var accuracy Accuracy
+		var mape, hitRate sql.NullFloat64
+		err := rows.Scan(
+			&accuracy.TradingCode,
+			&accuracy.Horizon,
+			&accuracy.Predictions,
+			&accuracy.Points,
+			&accuracy.MAE,
+			&accuracy.RMSE,
+			&mape,
+			&hitRate,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if mape.Valid {
+			accuracy.MAPE = &mape.Float64
+		}
+		if hitRate.Valid {
+			accuracy.HitRate = &hitRate.Float64
+		}
+		results = append(results, &accuracy)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type Stock struct {
@@ -25,12 +27,146 @@ type StockStore struct {
 	db *sql.DB
 }
 
-func (s *StockStore) Get(ctx context.Context) ([]*Stock, error) {
+// Get returns the stocks of the latest trading day matching the filter, along with pagination metadata
+func (s *StockStore) Get(ctx context.Context, filter StockFilter) ([]*Stock, Metadata, error) {
+	var stocks []*Stock
+	metadata, err := s.Stream(ctx, filter, func(stock *Stock) error {
+		stocks = append(stocks, stock)
+		return nil
+	})
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	return stocks, metadata, nil
+}
+
+// Stream calls fn for every stock of the latest trading day matching the filter as rows are read,
+// stopping at the first error fn returns
+func (s *StockStore) Stream(ctx context.Context, filter StockFilter, fn func(*Stock) error) (Metadata, error) {
+	where := `WHERE date = (SELECT MAX(date) FROM stock_history)
+                AND ($1::text[] IS NULL OR trading_code = ANY($1))
+                AND ($2::float8 IS NULL OR ltp >= $2)
+                AND ($3::float8 IS NULL OR ltp <= $3)
+                AND ($4::int IS NULL OR volume >= $4)
+                AND ($5::int IS NULL OR volume <= $5)`
+	args := []any{pq.Array(filter.Codes), filter.MinLtp, filter.MaxLtp, filter.MinVolume, filter.MaxVolume}
+
+	// counted separately so a page past the end still reports the real total
+	totalRecords := 0
+	countQuery := `SELECT count(*) FROM stock_history ` + where
+	if err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&totalRecords); err != nil {
+		return Metadata{}, err
+	}
+
 	query := `SELECT id, date, trading_code, ltp, high, low, openp, closep, ycp, trade, value, volume
               FROM stock_history
-              WHERE date = (SELECT MAX(date) FROM stock_history);
-	`
-	rows, err := s.db.QueryContext(ctx, query)
+              ` + where + `
+              ORDER BY ` + filter.orderBy() + `
+              LIMIT $6 OFFSET $7`
+	args = append(args, filter.limit(), filter.offset())
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var stock Stock
+		err := rows.Scan(
+			&stock.ID,
+			&stock.Date,
+			&stock.TradingCode,
+			&stock.Ltp,
+			&stock.High,
+			&stock.Low,
+			&stock.Openp,
+			&stock.Closep,
+			&stock.Ycp,
+			&stock.Trade,
+			&stock.Value,
+			&stock.Volume,
+		)
+		if err != nil {
+			return Metadata{}, err
+		}
+		if err := fn(&stock); err != nil {
+			return Metadata{}, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return Metadata{}, err
+	}
+
+	return calculateMetadata(totalRecords, filter.Page, filter.PageSize), nil
+}
+
+func (s *StockStore) GetByID(ctx context.Context, tradingCode string, start time.Time, end time.Time) ([]*Stock, error) {
+	var stocks []*Stock
+	err := s.StreamByID(ctx, tradingCode, start, end, func(stock *Stock) error {
+		stocks = append(stocks, stock)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stocks, nil
+}
+
+// StreamByID calls fn for every history row of a trading code as rows are read, stopping at the first error fn returns
+func (s *StockStore) StreamByID(ctx context.Context, tradingCode string, start time.Time, end time.Time, fn func(*Stock) error) error {
+	query := `SELECT id, date, trading_code, ltp, high, low, openp, closep, ycp, trade, value, volume
+              FROM stock_history
+              WHERE trading_code = $1 AND date >= $2 AND date <= $3
+              ORDER BY date ASC`
+	rows, err := s.db.QueryContext(ctx, query, tradingCode, start, end)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var stock Stock
+		err := rows.Scan(
+			&stock.ID,
+			&stock.Date,
+			&stock.TradingCode,
+			&stock.Ltp,
+			&stock.High,
+			&stock.Low,
+			&stock.Openp,
+			&stock.Closep,
+			&stock.Ycp,
+			&stock.Trade,
+			&stock.Value,
+			&stock.Volume,
+		)
+		if err != nil {
+			return err
+		}
+		if err := fn(&stock); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetByIDWithWarmup returns the same rows as GetByID plus up to warmup trading rows before start,
+// which indicator calculations need to produce stable values from the first requested date
+func (s *StockStore) GetByIDWithWarmup(ctx context.Context, tradingCode string, start time.Time, end time.Time, warmup int) ([]*Stock, error) {
+	query := `SELECT id, date, trading_code, ltp, high, low, openp, closep, ycp, trade, value, volume
+              FROM (
+                  (SELECT id, date, trading_code, ltp, high, low, openp, closep, ycp, trade, value, volume
+                   FROM stock_history
+                   WHERE trading_code = $1 AND date < $2
+                   ORDER BY date DESC
+                   LIMIT $4)
+                  UNION ALL
+                  (SELECT id, date, trading_code, ltp, high, low, openp, closep, ycp, trade, value, volume
+                   FROM stock_history
+                   WHERE trading_code = $1 AND date >= $2 AND date <= $3)
+              ) h
+              ORDER BY date ASC`
+	rows, err := s.db.QueryContext(ctx, query, tradingCode, start, end, warmup)
 	if err != nil {
 		return nil, err
 	}
@@ -61,16 +197,16 @@ func (s *StockStore) Get(ctx context.Context) ([]*Stock, error) {
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
-
 	return stocks, nil
 }
 
-func (s *StockStore) GetByID(ctx context.Context, tradingCode string, start time.Time, end time.Time) ([]*Stock, error) {
+// GetByCodes returns the rows of several trading codes in one query, ordered by date then trading code
+func (s *StockStore) GetByCodes(ctx context.Context, tradingCodes []string, start time.Time, end time.Time) ([]*Stock, error) {
 	query := `SELECT id, date, trading_code, ltp, high, low, openp, closep, ycp, trade, value, volume
               FROM stock_history
-              WHERE trading_code = $1 AND date >= $2 AND date <= $3
-              ORDER BY date ASC`
-	rows, err := s.db.QueryContext(ctx, query, tradingCode, start, end)
+              WHERE trading_code = ANY($1) AND date >= $2 AND date <= $3
+              ORDER BY date ASC, trading_code ASC`
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(tradingCodes), start, end)
 	if err != nil {
 		return nil, err
 	}
@@ -104,6 +240,50 @@ func (s *StockStore) GetByID(ctx context.Context, tradingCode string, start time
 	return stocks, nil
 }
 
+// StreamRange calls fn for every row between start and end of the given codes, or of all codes when
+// codes is empty, ordered by trading code, date and id and stopping at the first error fn returns.
+// Used for exports and full table scans such as the data quality report.
+func (s *StockStore) StreamRange(ctx context.Context, codes []string, start time.Time, end time.Time, fn func(*Stock) error) error {
+	query := `SELECT id, date, trading_code, ltp, high, low, openp, closep, ycp, trade, value, volume
+              FROM stock_history
+              WHERE date >= $1 AND date <= $2
+                AND (cardinality($3::text[]) = 0 OR trading_code = ANY($3))
+              ORDER BY trading_code ASC, date ASC, id ASC`
+	if codes == nil {
+		codes = []string{}
+	}
+	rows, err := s.db.QueryContext(ctx, query, start, end, pq.Array(codes))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var stock Stock
+		err := rows.Scan(
+			&stock.ID,
+			&stock.Date,
+			&stock.TradingCode,
+			&stock.Ltp,
+			&stock.High,
+			&stock.Low,
+			&stock.Openp,
+			&stock.Closep,
+			&stock.Ycp,
+			&stock.Trade,
+			&stock.Value,
+			&stock.Volume,
+		)
+		if err != nil {
+			return err
+		}
+		if err := fn(&stock); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
 func (s *StockStore) GetCurrentByID(ctx context.Context, tradingCode string) (*Stock, error) {
 	query := `SELECT id, date, trading_code, ltp, high, low, openp, closep, ycp, trade, value, volume
               FROM stock_history
@@ -16,19 +16,58 @@ var (
 
 type Storage struct {
 	Stocks interface {
-		Get(ctx context.Context) ([]*Stock, error)
+		Get(ctx context.Context, filter StockFilter) ([]*Stock, Metadata, error)
+		Stream(ctx context.Context, filter StockFilter, fn func(*Stock) error) (Metadata, error)
 		GetByID(ctx context.Context, tradingCode string, start time.Time, end time.Time) ([]*Stock, error)
+		StreamByID(ctx context.Context, tradingCode string, start time.Time, end time.Time, fn func(*Stock) error) error
+		StreamRange(ctx context.Context, codes []string, start time.Time, end time.Time, fn func(*Stock) error) error
+		GetByCodes(ctx context.Context, tradingCodes []string, start time.Time, end time.Time) ([]*Stock, error)
+		GetByIDWithWarmup(ctx context.Context, tradingCode string, start time.Time, end time.Time, warmup int) ([]*Stock, error)
 		GetCurrentByID(ctx context.Context, tradingCode string) (*Stock, error)
 	}
+	Calendar interface {
+		Get(ctx context.Context) ([]*CalendarDay, error)
+	}
+	Companies interface {
+		Get(ctx context.Context, sector string, category string) ([]*Company, error)
+		GetByCode(ctx context.Context, tradingCode string) (*Company, error)
+	}
+	CorporateActions interface {
+		GetByCode(ctx context.Context, tradingCode string) ([]*CorporateAction, error)
+		GetByCodes(ctx context.Context, tradingCodes []string) (map[string][]*CorporateAction, error)
+	}
+	Market interface {
+		Movers(ctx context.Context, date *time.Time, by string, limit int) ([]*Mover, error)
+		GetSummary(ctx context.Context, date *time.Time) (*MarketSummary, error)
+		GetSummaryHistory(ctx context.Context, start time.Time, end time.Time) ([]*MarketSummary, error)
+	}
+	PredictionJobs interface {
+		Create(ctx context.Context, job *PredictionJob) error
+		GetByID(ctx context.Context, id int64) (*PredictionJob, error)
+		Claim(ctx context.Context, lease time.Duration, maxAttempts int) (*PredictionJob, error)
+		Update(ctx context.Context, job *PredictionJob) error
+		Requeue(ctx context.Context, job *PredictionJob) error
+	}
 	Predictions interface {
 		GetHistory(ctx context.Context, tradingCode string, start time.Time, end time.Time) ([]*Stock, error)
+		GetHistoryBatch(ctx context.Context, tradingCodes []string, start time.Time, end time.Time) (map[string][]*Stock, error)
+		Create(ctx context.Context, prediction *Prediction) error
+		List(ctx context.Context, tradingCode string, since time.Time, limit int) ([]*Prediction, error)
+		FindLatest(ctx context.Context, tradingCode string, horizon int, adjusted bool, dataVersion string) (*Prediction, error)
+		Score(ctx context.Context) (int64, error)
+		Accuracy(ctx context.Context, filter AccuracyFilter) ([]*Accuracy, error)
 	}
 }
 
 func NewStorage(db *sql.DB) Storage {
 	return Storage{
-		Stocks:      &StockStore{db},
-		Predictions: &predictionStore{db},
+		Stocks:           &StockStore{db},
+		Calendar:         &CalendarStore{db},
+		Companies:        &CompanyStore{db},
+		Market:           &MarketStore{db},
+		CorporateActions: &CorporateActionStore{db},
+		Predictions:      &predictionStore{db},
+		PredictionJobs:   &PredictionJobStore{db},
 	}
 }
 
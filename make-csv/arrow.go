@@ -0,0 +1,139 @@
+package main
+
+import (
+	"io"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+// columnTypes are the typed columns written to parquet and arrow files
+var columnTypes = map[string]arrow.DataType{
+	"date":         arrow.FixedWidthTypes.Date32,
+	"trading_code": arrow.BinaryTypes.String,
+	"ltp":          arrow.PrimitiveTypes.Float64,
+	"high":         arrow.PrimitiveTypes.Float64,
+	"low":          arrow.PrimitiveTypes.Float64,
+	"openp":        arrow.PrimitiveTypes.Float64,
+	"closep":       arrow.PrimitiveTypes.Float64,
+	"ycp":          arrow.PrimitiveTypes.Float64,
+	"trade":        arrow.PrimitiveTypes.Int32,
+	"value":        arrow.PrimitiveTypes.Float64,
+	"volume":       arrow.PrimitiveTypes.Int32,
+}
+
+func newSchema(columns []string) *arrow.Schema {
+	fields := make([]arrow.Field, len(columns))
+	for i, column := range columns {
+		fields[i] = arrow.Field{Name: column, Type: columnTypes[column]}
+	}
+	return arrow.NewSchema(fields, nil)
+}
+
+// recordWriter is the part of the parquet and arrow IPC file writers the batch encoder needs
+type recordWriter interface {
+	Write(rec arrow.RecordBatch) error
+	Close() error
+}
+
+// batchEncoder buffers rows into a record batch per year, which becomes one parquet row group
+// or one arrow record batch, so readers can skip whole years
+type batchEncoder struct {
+	columns []string
+	builder *array.RecordBuilder
+	writer  recordWriter
+	year    int
+}
+
+func newParquetEncoder(w io.Writer, opts options) (*batchEncoder, error) {
+	codec := compress.Codecs.Snappy
+	if opts.gzip {
+		codec = compress.Codecs.Gzip
+	}
+	schema := newSchema(opts.columns)
+	props := parquet.NewWriterProperties(parquet.WithCompression(codec))
+	writer, err := pqarrow.NewFileWriter(schema, w, props, pqarrow.DefaultWriterProps())
+	if err != nil {
+		return nil, err
+	}
+	return newBatchEncoder(schema, opts.columns, writer), nil
+}
+
+func newArrowEncoder(w io.Writer, opts options) (*batchEncoder, error) {
+	schema := newSchema(opts.columns)
+	writer, err := ipc.NewFileWriter(w, ipc.WithSchema(schema))
+	if err != nil {
+		return nil, err
+	}
+	return newBatchEncoder(schema, opts.columns, writer), nil
+}
+
+func newBatchEncoder(schema *arrow.Schema, columns []string, writer recordWriter) *batchEncoder {
+	return &batchEncoder{
+		columns: columns,
+		builder: array.NewRecordBuilder(memory.DefaultAllocator, schema),
+		writer:  writer,
+	}
+}
+
+func (e *batchEncoder) Write(row StockRow) error {
+	if year := row.Date.Year(); year != e.year {
+		if err := e.flush(); err != nil {
+			return err
+		}
+		e.year = year
+	}
+
+	for i, column := range e.columns {
+		field := e.builder.Field(i)
+		switch column {
+		case "date":
+			field.(*array.Date32Builder).Append(arrow.Date32FromTime(row.Date))
+		case "trading_code":
+			field.(*array.StringBuilder).Append(row.TradingCode)
+		case "ltp":
+			field.(*array.Float64Builder).Append(row.Ltp)
+		case "high":
+			field.(*array.Float64Builder).Append(row.High)
+		case "low":
+			field.(*array.Float64Builder).Append(row.Low)
+		case "openp":
+			field.(*array.Float64Builder).Append(row.Openp)
+		case "closep":
+			field.(*array.Float64Builder).Append(row.Closep)
+		case "ycp":
+			field.(*array.Float64Builder).Append(row.Ycp)
+		case "trade":
+			field.(*array.Int32Builder).Append(int32(row.Trade))
+		case "value":
+			field.(*array.Float64Builder).Append(row.Value)
+		case "volume":
+			field.(*array.Int32Builder).Append(int32(row.Volume))
+		}
+	}
+	return nil
+}
+
+// flush writes the buffered rows of the current year as one batch
+func (e *batchEncoder) flush() error {
+	rec := e.builder.NewRecordBatch()
+	defer rec.Release()
+	if rec.NumRows() == 0 {
+		return nil
+	}
+	return e.writer.Write(rec)
+}
+
+func (e *batchEncoder) Close() error {
+	defer e.builder.Release()
+	if err := e.flush(); err != nil {
+		e.writer.Close()
+		return err
+	}
+	return e.writer.Close()
+}
@@ -2,9 +2,12 @@ package main
 
 import (
 	"database/sql"
-	"encoding/csv"
+	"flag"
 	"fmt"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	_ "github.com/lib/pq"
@@ -24,32 +27,98 @@ type StockRow struct {
 	Volume      int
 }
 
+// allColumns is the default column layout, also used by the API's CSV responses
+var allColumns = []string{"date", "trading_code", "ltp", "high", "low", "openp", "closep", "ycp", "trade", "value", "volume"}
+
+type options struct {
+	dsn     string
+	format  string
+	out     string
+	start   string
+	end     string
+	codes   []string
+	gzip    bool
+	splitBy string
+	columns []string
+}
+
+func parseOptions() (options, error) {
+	var opts options
+	var codes, columns string
+	flag.StringVar(&opts.dsn, "dsn", "user=stock_cast password=password dbname=stock_cast sslmode=disable", "Postgres connection string")
+	flag.StringVar(&opts.format, "format", "csv", "output format: csv, parquet or arrow")
+	flag.StringVar(&opts.out, "out", "", "output file, or output directory when --split-by is set (default stock_history.<format>)")
+	flag.StringVar(&opts.start, "start", "", "first date to export (YYYY-MM-DD)")
+	flag.StringVar(&opts.end, "end", "", "last date to export (YYYY-MM-DD)")
+	flag.StringVar(&codes, "codes", "", "comma-separated trading codes to export, all codes when empty")
+	flag.BoolVar(&opts.gzip, "gzip", false, "gzip compress the output, parquet files use gzip column compression")
+	flag.StringVar(&opts.splitBy, "split-by", "", "write one file per code or year")
+	flag.StringVar(&columns, "columns", strings.Join(allColumns, ","), "comma-separated columns to export, in order")
+	flag.Parse()
+
+	for _, date := range []string{opts.start, opts.end} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return opts, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
+		}
+	}
+	switch opts.format {
+	case formatCSV, formatParquet:
+	case formatArrow:
+		if opts.gzip {
+			return opts, fmt.Errorf("--gzip is not supported for arrow files")
+		}
+	default:
+		return opts, fmt.Errorf("--format must be csv, parquet or arrow")
+	}
+	if opts.out == "" {
+		opts.out = "stock_history." + opts.format
+		if opts.splitBy != "" {
+			opts.out = "stock_history"
+		}
+	}
+	if opts.splitBy != "" && opts.splitBy != "code" && opts.splitBy != "year" {
+		return opts, fmt.Errorf("--split-by must be code or year")
+	}
+	if codes != "" {
+		for _, code := range strings.Split(codes, ",") {
+			opts.codes = append(opts.codes, strings.ToUpper(strings.TrimSpace(code)))
+		}
+	}
+	for _, column := range strings.Split(columns, ",") {
+		column = strings.TrimSpace(column)
+		if !slices.Contains(allColumns, column) {
+			return opts, fmt.Errorf("unknown column %q, expected any of %s", column, strings.Join(allColumns, ","))
+		}
+		opts.columns = append(opts.columns, column)
+	}
+	return opts, nil
+}
+
 func main() {
-	db, err := sql.Open("postgres", "user=stock_cast password=password dbname=stock_cast sslmode=disable")
+	opts, err := parseOptions()
 	if err != nil {
-		panic(err)
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
-	defer db.Close()
 
-	rows, err := db.Query(`SELECT date, trading_code, ltp, high, low, openp, closep, ycp, trade, value, volume FROM stock_history ORDER BY date, trading_code`)
+	db, err := sql.Open("postgres", opts.dsn)
 	if err != nil {
 		panic(err)
 	}
-	defer rows.Close()
+	defer db.Close()
 
-	file, err := os.Create("stock_history.csv")
+	query, args := buildQuery(opts)
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		panic(err)
 	}
-	defer file.Close()
-
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	defer rows.Close()
 
-	// Write header
-	writer.Write([]string{
-		"date", "trading_code", "ltp", "high", "low", "openp", "closep", "ycp", "trade", "value", "volume",
-	})
+	sink := newSink(opts)
+	defer sink.Close()
 
 	count := 0
 	for rows.Next() {
@@ -71,20 +140,58 @@ func main() {
 			fmt.Println("Row scan error:", err)
 			continue
 		}
-		writer.Write([]string{
-			row.Date.Format("2006-01-02"),
-			row.TradingCode,
-			fmt.Sprintf("%f", row.Ltp),
-			fmt.Sprintf("%f", row.High),
-			fmt.Sprintf("%f", row.Low),
-			fmt.Sprintf("%f", row.Openp),
-			fmt.Sprintf("%f", row.Closep),
-			fmt.Sprintf("%f", row.Ycp),
-			fmt.Sprintf("%d", row.Trade),
-			fmt.Sprintf("%f", row.Value),
-			fmt.Sprintf("%d", row.Volume),
-		})
+		if err := sink.Write(row); err != nil {
+			panic(err)
+		}
 		count++
 	}
-	fmt.Printf("Exported %d rows to stock_history.csv\n", count)
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+	if err := sink.Close(); err != nil {
+		panic(err)
+	}
+	if count == 0 {
+		fmt.Println("No rows matched, nothing exported")
+		return
+	}
+	fmt.Printf("Exported %d rows to %s\n", count, strings.Join(sink.files, ", "))
+}
+
+// buildQuery selects the rows matching the date range and codes, ordered so split files are written one at a time
+func buildQuery(opts options) (string, []any) {
+	var conditions []string
+	var args []any
+	if opts.start != "" {
+		args = append(args, opts.start)
+		conditions = append(conditions, fmt.Sprintf("date >= $%d", len(args)))
+	}
+	if opts.end != "" {
+		args = append(args, opts.end)
+		conditions = append(conditions, fmt.Sprintf("date <= $%d", len(args)))
+	}
+	if len(opts.codes) > 0 {
+		placeholders := make([]string, len(opts.codes))
+		for i, code := range opts.codes {
+			args = append(args, code)
+			placeholders[i] = fmt.Sprintf("$%d", len(args))
+		}
+		conditions = append(conditions, "trading_code IN ("+strings.Join(placeholders, ",")+")")
+	}
+
+	query := `SELECT date, trading_code, ltp, high, low, openp, closep, ycp, trade, value, volume FROM stock_history`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if opts.splitBy == "code" {
+		query += " ORDER BY trading_code, date"
+	} else {
+		query += " ORDER BY date, trading_code"
+	}
+	return query, args
+}
+
+// formatDecimal prints a float with as many decimals as needed and no trailing zeros, so 286.6 stays 286.6
+func formatDecimal(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
 }
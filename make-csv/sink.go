@@ -0,0 +1,168 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	formatCSV     = "csv"
+	formatParquet = "parquet"
+	formatArrow   = "arrow"
+)
+
+// encoder writes rows of a single output file in one format
+type encoder interface {
+	Write(row StockRow) error
+	Close() error
+}
+
+// sink writes rows to a single file, or to one file per code or year when splitting.
+// Rows arrive ordered by the split key, so only the current file is kept open.
+type sink struct {
+	opts  options
+	key   string
+	file  *os.File
+	enc   encoder
+	files []string
+}
+
+func newSink(opts options) *sink {
+	return &sink{opts: opts}
+}
+
+func (s *sink) Write(row StockRow) error {
+	key := ""
+	switch s.opts.splitBy {
+	case "code":
+		key = row.TradingCode
+	case "year":
+		key = strconv.Itoa(row.Date.Year())
+	}
+
+	if s.enc == nil || key != s.key {
+		if err := s.Close(); err != nil {
+			return err
+		}
+		if err := s.open(key); err != nil {
+			return err
+		}
+	}
+	return s.enc.Write(row)
+}
+
+func (s *sink) open(key string) error {
+	path := s.opts.out
+	if s.opts.splitBy != "" {
+		if err := os.MkdirAll(s.opts.out, 0o755); err != nil {
+			return err
+		}
+		path = filepath.Join(s.opts.out, key+"."+s.opts.format)
+	}
+	if s.opts.format == formatCSV && s.opts.gzip && !strings.HasSuffix(path, ".gz") {
+		path += ".gz"
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	var enc encoder
+	switch s.opts.format {
+	case formatParquet:
+		enc, err = newParquetEncoder(file, s.opts)
+	case formatArrow:
+		enc, err = newArrowEncoder(file, s.opts)
+	default:
+		enc, err = newCSVEncoder(file, s.opts)
+	}
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	s.key = key
+	s.file = file
+	s.enc = enc
+	s.files = append(s.files, path)
+	return nil
+}
+
+// Close finishes and closes the current file, it is safe to call more than once
+func (s *sink) Close() error {
+	if s.enc == nil {
+		return nil
+	}
+	err := s.enc.Close()
+	// the parquet writer closes the file itself
+	if fileErr := s.file.Close(); err == nil && !errors.Is(fileErr, os.ErrClosed) {
+		err = fileErr
+	}
+	s.enc, s.file = nil, nil
+	return err
+}
+
+type csvEncoder struct {
+	columns []string
+	gz      *gzip.Writer
+	writer  *csv.Writer
+}
+
+func newCSVEncoder(w io.Writer, opts options) (*csvEncoder, error) {
+	enc := &csvEncoder{columns: opts.columns}
+	if opts.gzip {
+		enc.gz = gzip.NewWriter(w)
+		w = enc.gz
+	}
+	enc.writer = csv.NewWriter(w)
+	return enc, enc.writer.Write(opts.columns)
+}
+
+func (e *csvEncoder) Write(row StockRow) error {
+	record := make([]string, len(e.columns))
+	for i, column := range e.columns {
+		switch column {
+		case "date":
+			record[i] = row.Date.Format("2006-01-02")
+		case "trading_code":
+			record[i] = row.TradingCode
+		case "ltp":
+			record[i] = formatDecimal(row.Ltp)
+		case "high":
+			record[i] = formatDecimal(row.High)
+		case "low":
+			record[i] = formatDecimal(row.Low)
+		case "openp":
+			record[i] = formatDecimal(row.Openp)
+		case "closep":
+			record[i] = formatDecimal(row.Closep)
+		case "ycp":
+			record[i] = formatDecimal(row.Ycp)
+		case "trade":
+			record[i] = strconv.Itoa(row.Trade)
+		case "value":
+			record[i] = formatDecimal(row.Value)
+		case "volume":
+			record[i] = strconv.Itoa(row.Volume)
+		}
+	}
+	return e.writer.Write(record)
+}
+
+func (e *csvEncoder) Close() error {
+	e.writer.Flush()
+	err := e.writer.Error()
+	if e.gz != nil {
+		if gzErr := e.gz.Close(); err == nil {
+			err = gzErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CorporateAction is one row of the corporate actions file. Ratio is the number of new shares
+// per existing share, e.g. 0.1 for a 10% bonus or 0.5 for a 1R:2 rights issue.
+type CorporateAction struct {
+	TradingCode  string   `json:"tradingCode"`
+	RecordDate   string   `json:"recordDate"`
+	Type         string   `json:"type"`
+	Ratio        float64  `json:"ratio"`
+	IssuePrice   *float64 `json:"issuePrice"`
+	CashDividend float64  `json:"cashDividend"`
+}
+
+const upsertActionQuery = `INSERT INTO corporate_actions (trading_code, record_date, action_type, ratio, issue_price, cash_dividend)
+        VALUES ($1,$2,$3,$4,$5,$6)
+        ON CONFLICT (trading_code, record_date, action_type) DO UPDATE SET
+            ratio = EXCLUDED.ratio, issue_price = EXCLUDED.issue_price, cash_dividend = EXCLUDED.cash_dividend`
+
+var actionTypes = map[string]bool{"bonus": true, "rights": true, "split": true, "cash": true}
+
+// runActions loads bonus, rights, split and cash dividend records from a local CSV or JSON file
+func runActions(args []string) {
+	fs := flag.NewFlagSet("actions", flag.ExitOnError)
+	file := fs.String("file", "corporate_actions.csv", "CSV or JSON file with corporate actions")
+	fs.Parse(args)
+
+	f, err := os.Open(*file)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	var actions []CorporateAction
+	switch strings.ToLower(filepath.Ext(*file)) {
+	case ".json":
+		err = json.NewDecoder(f).Decode(&actions)
+	case ".csv":
+		actions, err = readActionsCSV(f)
+	default:
+		err = fmt.Errorf("unsupported file type %q, expected .csv or .json", filepath.Ext(*file))
+	}
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("Corporate actions read:", len(actions))
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	stmt, err := db.Prepare(upsertActionQuery)
+	if err != nil {
+		panic(err)
+	}
+	defer stmt.Close()
+
+	loaded := 0
+	for _, action := range actions {
+		actionType := strings.ToLower(strings.TrimSpace(action.Type))
+		if !actionTypes[actionType] {
+			fmt.Println("Skipping action with unknown type:", action.TradingCode, action.Type)
+			continue
+		}
+		recordDate, err := time.Parse("2006-01-02", action.RecordDate)
+		if err != nil {
+			fmt.Println("Skipping action due to error:", action.TradingCode, err)
+			continue
+		}
+
+		_, err = stmt.Exec(
+			strings.ToUpper(strings.TrimSpace(action.TradingCode)),
+			recordDate,
+			actionType,
+			action.Ratio,
+			action.IssuePrice,
+			action.CashDividend,
+		)
+		if err != nil {
+			fmt.Println("DB insert error:", action.TradingCode, err)
+			continue
+		}
+		loaded++
+	}
+	fmt.Printf("Corporate actions import complete. loaded=%d skipped=%d\n", loaded, len(actions)-loaded)
+}
+
+func readActionsCSV(r io.Reader) ([]CorporateAction, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"trading_code", "record_date", "type"} {
+		if _, ok := index[required]; !ok {
+			return nil, fmt.Errorf("corporate actions csv is missing the %q column", required)
+		}
+	}
+
+	var actions []CorporateAction
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			i, ok := index[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		number := func(name string) (float64, error) {
+			v := field(name)
+			if v == "" {
+				return 0, nil
+			}
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return 0, fmt.Errorf("%s: invalid %s %q", field("trading_code"), name, v)
+			}
+			return f, nil
+		}
+
+		action := CorporateAction{
+			TradingCode: field("trading_code"),
+			RecordDate:  field("record_date"),
+			Type:        field("type"),
+		}
+		if action.Ratio, err = number("ratio"); err != nil {
+			return nil, err
+		}
+		if action.CashDividend, err = number("cash_dividend"); err != nil {
+			return nil, err
+		}
+		if field("issue_price") != "" {
+			issuePrice, err := number("issue_price")
+			if err != nil {
+				return nil, err
+			}
+			action.IssuePrice = &issuePrice
+		}
+		actions = append(actions, action)
+	}
+	return actions, nil
+}
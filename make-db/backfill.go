@@ -0,0 +1,199 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"time"
+)
+
+// backfillJob names the checkpoints written by the backfill command
+const backfillJob = "backfill"
+
+type chunk struct {
+	start time.Time
+	end   time.Time
+}
+
+func (c chunk) String() string {
+	return c.start.Format("2006-01-02") + ".." + c.end.Format("2006-01-02")
+}
+
+// runBackfill walks the date range chunk by chunk, recording a checkpoint per completed chunk
+// so an interrupted run picks up where it stopped. Failed chunks are retried with backoff.
+func runBackfill(args []string) {
+	fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+	from := fs.String("from", "2015-01-01", "first date to backfill (YYYY-MM-DD)")
+	to := fs.String("to", "today", "last date to backfill (YYYY-MM-DD or today)")
+	chunkSize := fs.String("chunk", "month", "size of each request: day, week, month or year")
+	retries := fs.Int("retries", 5, "attempts per chunk before giving up on it")
+	api := fs.String("api", apiURL, "base URL of the bd-stock-api service")
+	force := fs.Bool("force", false, "re-import chunks that already have a checkpoint")
+	var load loadOptions
+	load.register(fs)
+	fs.Parse(args)
+
+	start, end, err := parseRange(*from, *to)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	chunks, err := splitChunks(start, end, *chunkSize)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	done, err := completedChunks(db)
+	if err != nil {
+		panic(err)
+	}
+
+	var total ImportSummary
+	var failed []chunk
+	for i, c := range chunks {
+		if done[c] && !*force {
+			fmt.Printf("[%d/%d] %s already imported, skipping\n", i+1, len(chunks), c)
+			continue
+		}
+
+		raws, err := fetchWithRetry(*api, c, *retries)
+		if err != nil {
+			fmt.Printf("[%d/%d] %s failed: %v\n", i+1, len(chunks), c, err)
+			failed = append(failed, c)
+			continue
+		}
+
+		summary, err := loadRows(db, "backfill "+c.String(), raws, load)
+		if err != nil {
+			fmt.Printf("[%d/%d] %s failed: %v\n", i+1, len(chunks), c, err)
+			failed = append(failed, c)
+			continue
+		}
+		total.Add(summary)
+
+		// a chunk with row errors is not checkpointed so the next run tries it again
+		if summary.Failed == 0 {
+			if err := saveCheckpoint(db, c, len(raws)); err != nil {
+				panic(err)
+			}
+		}
+		fmt.Printf("[%d/%d] %s rows=%d %s\n", i+1, len(chunks), c, len(raws), summary)
+	}
+
+	fmt.Println("Backfill complete.", total)
+	if err := refreshMarketSummaries(db); err != nil {
+		fmt.Println("Market summary refresh error:", err)
+	}
+	if len(failed) > 0 {
+		fmt.Printf("%d chunks failed, run the same command again to retry them:\n", len(failed))
+		for _, c := range failed {
+			fmt.Println(" ", c)
+		}
+		os.Exit(1)
+	}
+}
+
+func parseRange(from, to string) (time.Time, time.Time, error) {
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid --from date %q", from)
+	}
+
+	end := time.Now().UTC().Truncate(24 * time.Hour)
+	if to != "today" {
+		end, err = time.Parse("2006-01-02", to)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid --to date %q", to)
+		}
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, errors.New("--to must not be before --from")
+	}
+	return start, end, nil
+}
+
+// splitChunks cuts [start, end] into calendar aligned chunks, the first and last may be partial
+func splitChunks(start, end time.Time, size string) ([]chunk, error) {
+	next := map[string]func(time.Time) time.Time{
+		"day":   func(t time.Time) time.Time { return t.AddDate(0, 0, 1) },
+		"week":  func(t time.Time) time.Time { return t.AddDate(0, 0, 7-int(t.Weekday())) },
+		"month": func(t time.Time) time.Time { return time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC) },
+		"year":  func(t time.Time) time.Time { return time.Date(t.Year()+1, 1, 1, 0, 0, 0, 0, time.UTC) },
+	}[size]
+	if next == nil {
+		return nil, fmt.Errorf("--chunk must be day, week, month or year")
+	}
+
+	var chunks []chunk
+	for cur := start; !cur.After(end); {
+		following := next(cur)
+		last := following.AddDate(0, 0, -1)
+		if last.After(end) {
+			last = end
+		}
+		chunks = append(chunks, chunk{start: cur, end: last})
+		cur = following
+	}
+	return chunks, nil
+}
+
+// fetchWithRetry retries connection errors and 5xx answers with exponential backoff and jitter
+func fetchWithRetry(api string, c chunk, attempts int) ([]RawStockRow, error) {
+	backoff := 2 * time.Second
+	for attempt := 1; ; attempt++ {
+		raws, err := fetchHistorical(api, c.start.Format("2006-01-02"), c.end.Format("2006-01-02"))
+		if err == nil {
+			return raws, nil
+		}
+
+		var statusErr *statusError
+		retryable := !errors.As(err, &statusErr) || statusErr.code >= http.StatusInternalServerError || statusErr.code == http.StatusTooManyRequests
+		if !retryable || attempt >= attempts {
+			return nil, err
+		}
+
+		wait := backoff + rand.N(backoff/2)
+		fmt.Printf("    %s attempt %d failed (%v), retrying in %s\n", c, attempt, err, wait.Round(time.Millisecond))
+		time.Sleep(wait)
+		backoff = min(backoff*2, time.Minute)
+	}
+}
+
+func completedChunks(db *sql.DB) (map[chunk]bool, error) {
+	rows, err := db.Query(`SELECT chunk_start, chunk_end FROM ingest_checkpoints WHERE job = $1`, backfillJob)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	done := map[chunk]bool{}
+	for rows.Next() {
+		var c chunk
+		if err := rows.Scan(&c.start, &c.end); err != nil {
+			return nil, err
+		}
+		c.start, c.end = c.start.UTC(), c.end.UTC()
+		done[c] = true
+	}
+	return done, rows.Err()
+}
+
+func saveCheckpoint(db *sql.DB, c chunk, rows int) error {
+	_, err := db.Exec(`INSERT INTO ingest_checkpoints (job, chunk_start, chunk_end, rows_received)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (job, chunk_start, chunk_end) DO UPDATE SET
+            rows_received = EXCLUDED.rows_received, completed_at = NOW()`,
+		backfillJob, c.start, c.end, rows)
+	return err
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+
+	"github.com/lib/pq"
+)
+
+// createStagingQuery creates a per transaction staging table, ord keeps the COPY order so the
+// last row wins when an import holds the same (trading_code, date) twice
+const createStagingQuery = `CREATE TEMP TABLE stock_history_staging (
+            ord BIGSERIAL,
+            date DATE NOT NULL,
+            trading_code VARCHAR(20) NOT NULL,
+            ltp DOUBLE PRECISION NOT NULL,
+            high DOUBLE PRECISION NOT NULL,
+            low DOUBLE PRECISION NOT NULL,
+            openp DOUBLE PRECISION NOT NULL,
+            closep DOUBLE PRECISION NOT NULL,
+            ycp DOUBLE PRECISION NOT NULL,
+            trade INTEGER NOT NULL,
+            value DOUBLE PRECISION NOT NULL,
+            volume INTEGER NOT NULL
+        ) ON COMMIT DROP`
+
+const mergeStagingQuery = `INSERT INTO stock_history (date, trading_code, ltp, high, low, openp, closep, ycp, trade, value, volume)
+        SELECT DISTINCT ON (trading_code, date) date, trading_code, ltp, high, low, openp, closep, ycp, trade, value, volume
+        FROM stock_history_staging
+        ORDER BY trading_code, date, ord DESC` + upsertConflict
+
+var stagingColumns = []string{"date", "trading_code", "ltp", "high", "low", "openp", "closep", "ycp", "trade", "value", "volume"}
+
+// bulkImportRows validates raw rows, streams them into a temp table with one COPY per batch and merges
+// everything into stock_history, all in a single transaction. Any failure rolls back the whole import.
+func bulkImportRows(db *sql.DB, source string, raws []RawStockRow, batchSize int) (ImportSummary, error) {
+	var summary ImportSummary
+	if batchSize < 1 {
+		batchSize = 5000
+	}
+
+	rows, err := validRows(db, source, raws, &summary)
+	if err != nil {
+		return summary, err
+	}
+	if len(rows) == 0 {
+		return summary, nil
+	}
+
+	ctx := context.Background()
+	var merged ImportSummary
+	err = withTx(db, ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, createStagingQuery); err != nil {
+			return err
+		}
+		for start := 0; start < len(rows); start += batchSize {
+			batch := rows[start:min(start+batchSize, len(rows))]
+			if err := copyBatch(ctx, tx, batch); err != nil {
+				return err
+			}
+
+			done := start + len(batch)
+			fmt.Fprintf(os.Stderr, "\r    %s: %d/%d rows staged (%.0f%%)", source, done, len(rows), float64(done)/float64(len(rows))*100)
+		}
+		fmt.Fprintf(os.Stderr, "\n    %s: merging %d rows\n", source, len(rows))
+		return mergeStaging(ctx, tx, &merged)
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr)
+		summary.Failed += len(rows)
+		return summary, fmt.Errorf("bulk import rolled back: %w", err)
+	}
+	summary.Add(merged)
+	return summary, nil
+}
+
+// copyBatch streams one batch into the staging table with COPY
+func copyBatch(ctx context.Context, tx *sql.Tx, batch []StockRow) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("stock_history_staging", stagingColumns...))
+	if err != nil {
+		return err
+	}
+	for _, row := range batch {
+		_, err := stmt.ExecContext(ctx, row.Date, row.TradingCode, row.Ltp, row.High, row.Low, row.Openp, row.Closep, row.Ycp, row.Trade, row.Value, row.Volume)
+		if err != nil {
+			stmt.Close()
+			return err
+		}
+	}
+	// an empty Exec flushes the buffered COPY data
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return err
+	}
+	return stmt.Close()
+}
+
+// mergeStaging upserts the staged rows into stock_history and counts what changed
+func mergeStaging(ctx context.Context, tx *sql.Tx, summary *ImportSummary) error {
+	var distinct int
+	err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM (SELECT DISTINCT trading_code, date FROM stock_history_staging) s`).Scan(&distinct)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.QueryContext(ctx, mergeStagingQuery)
+	if err != nil {
+		return err
+	}
+	defer result.Close()
+
+	changed := 0
+	for result.Next() {
+		var inserted bool
+		if err := result.Scan(&inserted); err != nil {
+			return err
+		}
+		if inserted {
+			summary.Inserted++
+		} else {
+			summary.Updated++
+		}
+		changed++
+	}
+	if err := result.Err(); err != nil {
+		return err
+	}
+	summary.Unchanged += distinct - changed
+	return nil
+}
+
+func withTx(db *sql.DB, ctx context.Context, fn func(*sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
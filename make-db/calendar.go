@@ -0,0 +1,126 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CalendarDay is one row of the trading calendar file. Days default to holidays,
+// set isTradingDay for special sessions held on a Friday or Saturday.
+type CalendarDay struct {
+	Date         string `json:"date"`
+	IsTradingDay bool   `json:"isTradingDay"`
+	Description  string `json:"description"`
+}
+
+const upsertCalendarQuery = `INSERT INTO trading_calendar (date, is_trading_day, description)
+        VALUES ($1,$2,$3)
+        ON CONFLICT (date) DO UPDATE SET
+            is_trading_day = EXCLUDED.is_trading_day, description = EXCLUDED.description`
+
+// runCalendar loads DSE holidays and special sessions from a local CSV or JSON file
+func runCalendar(args []string) {
+	fs := flag.NewFlagSet("calendar", flag.ExitOnError)
+	file := fs.String("file", "trading_calendar.csv", "CSV or JSON file with holidays and special sessions")
+	fs.Parse(args)
+
+	f, err := os.Open(*file)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	var days []CalendarDay
+	switch strings.ToLower(filepath.Ext(*file)) {
+	case ".json":
+		err = json.NewDecoder(f).Decode(&days)
+	case ".csv":
+		days, err = readCalendarCSV(f)
+	default:
+		err = fmt.Errorf("unsupported file type %q, expected .csv or .json", filepath.Ext(*file))
+	}
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("Calendar days read:", len(days))
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	stmt, err := db.Prepare(upsertCalendarQuery)
+	if err != nil {
+		panic(err)
+	}
+	defer stmt.Close()
+
+	loaded := 0
+	for _, day := range days {
+		date, err := time.Parse("2006-01-02", strings.TrimSpace(day.Date))
+		if err != nil {
+			fmt.Println("Skipping calendar day due to error:", err)
+			continue
+		}
+		if _, err := stmt.Exec(date, day.IsTradingDay, strings.TrimSpace(day.Description)); err != nil {
+			fmt.Println("DB insert error:", day.Date, err)
+			continue
+		}
+		loaded++
+	}
+	fmt.Printf("Calendar import complete. loaded=%d skipped=%d\n", loaded, len(days)-loaded)
+}
+
+func readCalendarCSV(r io.Reader) ([]CalendarDay, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := index["date"]; !ok {
+		return nil, fmt.Errorf("calendar csv is missing the %q column", "date")
+	}
+
+	var days []CalendarDay
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			i, ok := index[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		day := CalendarDay{Date: field("date"), Description: field("description")}
+		if v := field("is_trading_day"); v != "" {
+			day.IsTradingDay, err = strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid is_trading_day %q", day.Date, v)
+			}
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
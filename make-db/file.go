@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	fileFormatAuto  = "auto"
+	fileFormatRaw   = "raw"
+	fileFormatCamel = "camel"
+	fileFormatCSV   = "csv"
+)
+
+// camelStockRow is the shape of bd-stock-api's sample_history.json and of the backend API,
+// values may be either JSON strings or numbers
+type camelStockRow struct {
+	Date        flexString `json:"date"`
+	TradingCode flexString `json:"tradingCode"`
+	Ltp         flexString `json:"ltp"`
+	High        flexString `json:"high"`
+	Low         flexString `json:"low"`
+	Openp       flexString `json:"openp"`
+	Closep      flexString `json:"closep"`
+	Ycp         flexString `json:"ycp"`
+	Trade       flexString `json:"trade"`
+	Value       flexString `json:"value"`
+	Volume      flexString `json:"volume"`
+}
+
+// flexString accepts a JSON string or number and keeps its text
+type flexString string
+
+func (f *flexString) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		*f = flexString(s)
+		return nil
+	}
+	if string(b) == "null" {
+		*f = ""
+		return nil
+	}
+	*f = flexString(b)
+	return nil
+}
+
+// runFile imports local dumps instead of calling the scraper service, the format of each file
+// is detected from its extension and content unless --format is given
+func runFile(args []string) {
+	fs := flag.NewFlagSet("file", flag.ExitOnError)
+	format := fs.String("format", fileFormatAuto, "file format: auto, raw (scraper JSON), camel (camelCase JSON) or csv (make-csv output)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: make-db file [--format auto|raw|camel|csv] FILE...")
+		fs.PrintDefaults()
+	}
+	var load loadOptions
+	load.register(fs)
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	var total ImportSummary
+	for _, path := range fs.Args() {
+		raws, detected, err := readRowsFile(path, *format)
+		if err != nil {
+			fmt.Printf("%s: %v\n", path, err)
+			os.Exit(1)
+		}
+		fmt.Printf("%s: %d rows read as %s\n", path, len(raws), detected)
+
+		summary, err := loadRows(db, "file "+path, raws, load)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Printf("%s: %s\n", path, summary)
+		total.Add(summary)
+	}
+	fmt.Println("File import complete.", total)
+
+	if err := refreshMarketSummaries(db); err != nil {
+		fmt.Println("Market summary refresh error:", err)
+	}
+}
+
+// readRowsFile reads a JSON or CSV dump, gzip compressed files ending in .gz are supported.
+// It returns the rows in the scraper's shape so every source goes through the same conversion.
+func readRowsFile(path, format string) ([]RawStockRow, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	name := path
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, "", err
+		}
+		defer gz.Close()
+		r = gz
+		name = strings.TrimSuffix(name, ".gz")
+	}
+
+	br := bufio.NewReader(r)
+	if format == fileFormatAuto {
+		format, err = detectFormat(name, br)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	var raws []RawStockRow
+	switch format {
+	case fileFormatCSV:
+		raws, err = readCSVRows(br)
+	case fileFormatRaw:
+		raws, err = readRawRows(br)
+	case fileFormatCamel:
+		raws, err = readCamelRows(br)
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	return raws, format, err
+}
+
+// detectFormat looks at the extension and the first JSON object to tell the formats apart
+func detectFormat(name string, br *bufio.Reader) (string, error) {
+	if strings.HasSuffix(strings.ToLower(name), ".csv") {
+		return fileFormatCSV, nil
+	}
+
+	head, err := br.Peek(4096)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return "", err
+	}
+	trimmed := bytes.TrimSpace(head)
+	switch {
+	case len(trimmed) == 0:
+		return "", fmt.Errorf("file is empty")
+	case trimmed[0] != '[' && trimmed[0] != '{':
+		return fileFormatCSV, nil
+	case bytes.Contains(head, []byte(`"TRADING CODE"`)):
+		return fileFormatRaw, nil
+	case bytes.Contains(head, []byte(`"tradingCode"`)):
+		return fileFormatCamel, nil
+	}
+	return "", fmt.Errorf("could not detect the file format, use --format")
+}
+
+// readRawRows accepts the scraper's {"data": [...]} answer or a bare array of its rows
+func readRawRows(r io.Reader) ([]RawStockRow, error) {
+	var payload json.RawMessage
+	if err := json.NewDecoder(r).Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	var raws []RawStockRow
+	if trimmed := bytes.TrimSpace(payload); len(trimmed) > 0 && trimmed[0] == '{' {
+		var wrapped struct {
+			Data []RawStockRow `json:"data"`
+		}
+		err := json.Unmarshal(payload, &wrapped)
+		return wrapped.Data, err
+	}
+	err := json.Unmarshal(payload, &raws)
+	return raws, err
+}
+
+// readCamelRows accepts a bare array of camelCase rows or an object wrapping it under data, history or stocks
+func readCamelRows(r io.Reader) ([]RawStockRow, error) {
+	var payload json.RawMessage
+	if err := json.NewDecoder(r).Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	var rows []camelStockRow
+	if trimmed := bytes.TrimSpace(payload); len(trimmed) > 0 && trimmed[0] == '{' {
+		var wrapped map[string]json.RawMessage
+		if err := json.Unmarshal(payload, &wrapped); err != nil {
+			return nil, err
+		}
+		for _, key := range []string{"data", "history", "stocks"} {
+			if inner, ok := wrapped[key]; ok {
+				payload = inner
+				break
+			}
+		}
+	}
+	if err := json.Unmarshal(payload, &rows); err != nil {
+		return nil, err
+	}
+
+	raws := make([]RawStockRow, len(rows))
+	for i, row := range rows {
+		raws[i] = RawStockRow{
+			// the API writes full timestamps, only the date part is kept
+			Date:        strings.SplitN(string(row.Date), "T", 2)[0],
+			TradingCode: string(row.TradingCode),
+			Ltp:         string(row.Ltp),
+			High:        string(row.High),
+			Low:         string(row.Low),
+			Openp:       string(row.Openp),
+			Closep:      string(row.Closep),
+			Ycp:         string(row.Ycp),
+			Trade:       string(row.Trade),
+			Value:       string(row.Value),
+			Volume:      string(row.Volume),
+		}
+	}
+	return raws, nil
+}
+
+// readCSVRows reads the column layout make-csv writes, columns may come in any order
+func readCSVRows(r io.Reader) ([]RawStockRow, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	columns := []string{"date", "trading_code", "ltp", "high", "low", "openp", "closep", "ycp", "trade", "value", "volume"}
+	for _, column := range columns {
+		if _, ok := index[column]; !ok {
+			return nil, fmt.Errorf("csv is missing the %q column", column)
+		}
+	}
+
+	var raws []RawStockRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			return strings.TrimSpace(record[index[name]])
+		}
+		raws = append(raws, RawStockRow{
+			Date:        field("date"),
+			TradingCode: field("trading_code"),
+			Ltp:         field("ltp"),
+			High:        field("high"),
+			Low:         field("low"),
+			Openp:       field("openp"),
+			Closep:      field("closep"),
+			Ycp:         field("ycp"),
+			Trade:       field("trade"),
+			Value:       field("value"),
+			Volume:      field("volume"),
+		})
+	}
+	return raws, nil
+}
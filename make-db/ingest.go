@@ -0,0 +1,207 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// upsertConflict refreshes an existing (trading_code, date) row. Rows whose values did not change
+// are left untouched and return no result, otherwise xmax = 0 tells a fresh insert apart from an update.
+const upsertConflict = `
+        ON CONFLICT (trading_code, date) DO UPDATE SET
+            ltp = EXCLUDED.ltp, high = EXCLUDED.high, low = EXCLUDED.low, openp = EXCLUDED.openp,
+            closep = EXCLUDED.closep, ycp = EXCLUDED.ycp, trade = EXCLUDED.trade,
+            value = EXCLUDED.value, volume = EXCLUDED.volume
+        WHERE (stock_history.ltp, stock_history.high, stock_history.low, stock_history.openp,
+               stock_history.closep, stock_history.ycp, stock_history.trade, stock_history.value, stock_history.volume)
+            IS DISTINCT FROM
+              (EXCLUDED.ltp, EXCLUDED.high, EXCLUDED.low, EXCLUDED.openp,
+               EXCLUDED.closep, EXCLUDED.ycp, EXCLUDED.trade, EXCLUDED.value, EXCLUDED.volume)
+        RETURNING (xmax = 0) AS inserted`
+
+// upsertQuery inserts a single row or refreshes the existing one
+const upsertQuery = `INSERT INTO stock_history (date, trading_code, ltp, high, low, openp, closep, ycp, trade, value, volume)
+        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)` + upsertConflict
+
+type ImportSummary struct {
+	Inserted  int
+	Updated   int
+	Unchanged int
+	Rejected  int
+	Failed    int
+	// Reasons counts rejected rows per rejection reason
+	Reasons map[string]int
+}
+
+func (s ImportSummary) String() string {
+	out := fmt.Sprintf("inserted=%d updated=%d unchanged=%d rejected=%d failed=%d",
+		s.Inserted, s.Updated, s.Unchanged, s.Rejected, s.Failed)
+	if len(s.Reasons) > 0 {
+		reasons := make([]string, 0, len(s.Reasons))
+		for reason, count := range s.Reasons {
+			reasons = append(reasons, fmt.Sprintf("%s=%d", reason, count))
+		}
+		sort.Strings(reasons)
+		out += " (" + strings.Join(reasons, " ") + ")"
+	}
+	return out
+}
+
+// Add accumulates the counts of another import into s
+func (s *ImportSummary) Add(other ImportSummary) {
+	s.Inserted += other.Inserted
+	s.Updated += other.Updated
+	s.Unchanged += other.Unchanged
+	s.Failed += other.Failed
+	for reason, count := range other.Reasons {
+		s.reject(reason, count)
+	}
+}
+
+func (s *ImportSummary) reject(reason string, count int) {
+	if s.Reasons == nil {
+		s.Reasons = map[string]int{}
+	}
+	s.Rejected += count
+	s.Reasons[reason] += count
+}
+
+func upsert(stmt *sql.Stmt, row StockRow, summary *ImportSummary) error {
+	var inserted bool
+	err := stmt.QueryRow(row.Date, row.TradingCode, row.Ltp, row.High, row.Low, row.Openp, row.Closep, row.Ycp, row.Trade, row.Value, row.Volume).Scan(&inserted)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		summary.Unchanged++
+	case err != nil:
+		return err
+	case inserted:
+		summary.Inserted++
+	default:
+		summary.Updated++
+	}
+	return nil
+}
+
+// loadOptions chooses between the row by row upsert and the COPY based bulk path
+type loadOptions struct {
+	bulk      bool
+	batchSize int
+}
+
+func (o *loadOptions) register(fs *flag.FlagSet) {
+	fs.BoolVar(&o.bulk, "bulk", false, "stage rows with COPY and merge them in batches, much faster for large imports")
+	fs.IntVar(&o.batchSize, "batch-size", 5000, "rows per COPY round trip when --bulk is set, the whole import is still one transaction")
+}
+
+// loadRows imports raw rows through the path chosen by opts
+func loadRows(db *sql.DB, source string, raws []RawStockRow, opts loadOptions) (ImportSummary, error) {
+	if opts.bulk {
+		return bulkImportRows(db, source, raws, opts.batchSize)
+	}
+	return importRows(db, source, raws)
+}
+
+// validRows converts and validates raw rows. Rows failing validation are written to ingest_rejects
+// along with their payload, source and reason instead of being imported.
+func validRows(db *sql.DB, source string, raws []RawStockRow, summary *ImportSummary) ([]StockRow, error) {
+	rejectStmt, err := db.Prepare(insertRejectQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rejectStmt.Close()
+
+	rows := make([]StockRow, 0, len(raws))
+	for _, raw := range raws {
+		row, err := convert(raw)
+		if err == nil {
+			err = validateRow(row)
+		}
+		if err != nil {
+			var rejectErr *rejectError
+			if !errors.As(err, &rejectErr) {
+				return nil, err
+			}
+			summary.reject(rejectErr.reason, 1)
+			if err := saveReject(rejectStmt, source, raw, rejectErr); err != nil {
+				fmt.Println("DB reject insert error:", err)
+			}
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// importRows validates and upserts raw rows one by one
+func importRows(db *sql.DB, source string, raws []RawStockRow) (ImportSummary, error) {
+	var summary ImportSummary
+
+	rows, err := validRows(db, source, raws, &summary)
+	if err != nil {
+		return summary, err
+	}
+
+	stmt, err := db.Prepare(upsertQuery)
+	if err != nil {
+		return summary, err
+	}
+	defer stmt.Close()
+
+	for _, row := range rows {
+		if err := upsert(stmt, row, &summary); err != nil {
+			fmt.Println("DB insert error:", err)
+			summary.Failed++
+		}
+	}
+	return summary, nil
+}
+
+// fetchHistorical pulls the rows between start and end (inclusive) from the bd-stock-api service
+func fetchHistorical(api, start, end string) ([]RawStockRow, error) {
+	client := &http.Client{Timeout: 5 * time.Minute}
+
+	u := fmt.Sprintf("%s/v1/dse/historical?start=%s&end=%s", api, url.QueryEscape(start), url.QueryEscape(end))
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, &statusError{code: resp.StatusCode, body: string(body)}
+	}
+
+	var result struct {
+		Data []RawStockRow `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+// statusError is a non-200 answer from the scraper service
+type statusError struct {
+	code int
+	body string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.code, e.body)
+}
+
+// refreshMarketSummaries recomputes the per-day market breadth view after stock_history changed
+func refreshMarketSummaries(db *sql.DB) error {
+	_, err := db.Exec(`REFRESH MATERIALIZED VIEW CONCURRENTLY market_summaries`)
+	return err
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Instrument is one row of the company metadata file. JSON files use the same
+// camelCase keys the /v1/companies API returns, CSV files use the snake_case column names.
+type Instrument struct {
+	TradingCode string   `json:"tradingCode"`
+	Name        string   `json:"name"`
+	Sector      string   `json:"sector"`
+	Category    string   `json:"category"`
+	ListingDate string   `json:"listingDate"`
+	FaceValue   *float64 `json:"faceValue"`
+	TotalShares *int64   `json:"totalShares"`
+}
+
+var instrumentColumns = []string{"trading_code", "company_name", "sector", "category", "listing_date", "face_value", "total_shares"}
+
+const upsertInstrumentQuery = `INSERT INTO instruments (trading_code, company_name, sector, category, listing_date, face_value, total_shares)
+        VALUES ($1,$2,$3,$4,$5,$6,$7)
+        ON CONFLICT (trading_code) DO UPDATE SET
+            company_name = EXCLUDED.company_name, sector = EXCLUDED.sector, category = EXCLUDED.category,
+            listing_date = EXCLUDED.listing_date, face_value = EXCLUDED.face_value,
+            total_shares = EXCLUDED.total_shares, updated_at = NOW()`
+
+// runInstruments loads company metadata from a local CSV or JSON file into the instruments table
+func runInstruments(args []string) {
+	fs := flag.NewFlagSet("instruments", flag.ExitOnError)
+	file := fs.String("file", "instruments.csv", "CSV or JSON file with company metadata")
+	fs.Parse(args)
+
+	f, err := os.Open(*file)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	var instruments []Instrument
+	switch strings.ToLower(filepath.Ext(*file)) {
+	case ".json":
+		err = json.NewDecoder(f).Decode(&instruments)
+	case ".csv":
+		instruments, err = readInstrumentsCSV(f)
+	default:
+		err = fmt.Errorf("unsupported file type %q, expected .csv or .json", filepath.Ext(*file))
+	}
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println("Instruments read:", len(instruments))
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	stmt, err := db.Prepare(upsertInstrumentQuery)
+	if err != nil {
+		panic(err)
+	}
+	defer stmt.Close()
+
+	loaded := 0
+	for _, ins := range instruments {
+		if ins.TradingCode == "" || ins.Name == "" {
+			fmt.Println("Skipping instrument without trading code or name:", ins)
+			continue
+		}
+
+		var listingDate *time.Time
+		if ins.ListingDate != "" {
+			d, err := time.Parse("2006-01-02", ins.ListingDate)
+			if err != nil {
+				fmt.Println("Skipping instrument due to error:", ins.TradingCode, err)
+				continue
+			}
+			listingDate = &d
+		}
+
+		_, err := stmt.Exec(
+			strings.ToUpper(strings.TrimSpace(ins.TradingCode)),
+			strings.TrimSpace(ins.Name),
+			strings.TrimSpace(ins.Sector),
+			strings.ToUpper(strings.TrimSpace(ins.Category)),
+			listingDate,
+			ins.FaceValue,
+			ins.TotalShares,
+		)
+		if err != nil {
+			fmt.Println("DB insert error:", ins.TradingCode, err)
+			continue
+		}
+		loaded++
+	}
+	fmt.Printf("Instruments import complete. loaded=%d skipped=%d\n", loaded, len(instruments)-loaded)
+}
+
+func readInstrumentsCSV(r io.Reader) ([]Instrument, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		index[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range instrumentColumns[:2] {
+		if _, ok := index[required]; !ok {
+			return nil, fmt.Errorf("instruments csv is missing the %q column", required)
+		}
+	}
+
+	var instruments []Instrument
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		field := func(name string) string {
+			i, ok := index[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		ins := Instrument{
+			TradingCode: field("trading_code"),
+			Name:        field("company_name"),
+			Sector:      field("sector"),
+			Category:    field("category"),
+			ListingDate: field("listing_date"),
+		}
+		if v := field("face_value"); v != "" {
+			faceValue, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid face_value %q", ins.TradingCode, v)
+			}
+			ins.FaceValue = &faceValue
+		}
+		if v := field("total_shares"); v != "" {
+			totalShares, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid total_shares %q", ins.TradingCode, v)
+			}
+			ins.TotalShares = &totalShares
+		}
+		instruments = append(instruments, ins)
+	}
+	return instruments, nil
+}
@@ -2,10 +2,10 @@ package main
 
 import (
 	"database/sql"
-	"encoding/json"
+	"flag"
 	"fmt"
-	"net/http"
-	"strconv"
+	"os"
+	"strings"
 	"time"
 
 	_ "github.com/lib/pq"
@@ -39,128 +39,98 @@ type StockRow struct {
 	Volume      int
 }
 
+// convert parses every field of a raw row, any field that is not a valid number rejects the whole row
 func convert(raw RawStockRow) (StockRow, error) {
-	date, err := time.Parse("2006-01-02", raw.Date)
+	date, err := time.Parse("2006-01-02", strings.TrimSpace(raw.Date))
 	if err != nil {
-		return StockRow{}, err
+		return StockRow{}, reject(reasonParse, "date: invalid date %q", raw.Date)
+	}
+	tradingCode := strings.TrimSpace(raw.TradingCode)
+	if tradingCode == "" {
+		return StockRow{}, reject(reasonParse, "trading code is empty")
 	}
-	ltp, _ := strconv.ParseFloat(raw.Ltp, 64)
-	high, _ := strconv.ParseFloat(raw.High, 64)
-	low, _ := strconv.ParseFloat(raw.Low, 64)
-	openp, _ := strconv.ParseFloat(raw.Openp, 64)
-	closep, _ := strconv.ParseFloat(raw.Closep, 64)
-	ycp, _ := strconv.ParseFloat(raw.Ycp, 64)
-	trade, _ := strconv.Atoi(raw.Trade)
-	value, _ := strconv.ParseFloat(raw.Value, 64)
-	volume, _ := strconv.Atoi(raw.Volume)
 
-	return StockRow{
+	p := parser{}
+	row := StockRow{
 		Date:        date,
-		TradingCode: raw.TradingCode,
-		Ltp:         ltp,
-		High:        high,
-		Low:         low,
-		Openp:       openp,
-		Closep:      closep,
-		Ycp:         ycp,
-		Trade:       trade,
-		Value:       value,
-		Volume:      volume,
-	}, nil
+		TradingCode: tradingCode,
+		Ltp:         p.float("ltp", raw.Ltp),
+		High:        p.float("high", raw.High),
+		Low:         p.float("low", raw.Low),
+		Openp:       p.float("openp", raw.Openp),
+		Closep:      p.float("closep", raw.Closep),
+		Ycp:         p.float("ycp", raw.Ycp),
+		Trade:       p.int("trade", raw.Trade),
+		Value:       p.float("value", raw.Value),
+		Volume:      p.int("volume", raw.Volume),
+	}
+	if p.err != nil {
+		return StockRow{}, p.err
+	}
+	return row, nil
 }
 
+// dsn points at the local development database the backend migrations run against
+const dsn = "user=stock_cast password=password dbname=stock_cast sslmode=disable"
+
+// apiURL is the bd-stock-api scraper service the historical rows are pulled from
+const apiURL = "http://localhost:3000"
+
 func main() {
-	resp, err := http.Get("http://localhost:3000/v1/dse/historical?start=2025-08-01&end=2025-08-17")
-	fmt.Println(resp.StatusCode, resp.Header)
-	if err != nil {
-		panic(err)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "import":
+			runImport(os.Args[2:])
+			return
+		case "backfill":
+			runBackfill(os.Args[2:])
+			return
+		case "file":
+			runFile(os.Args[2:])
+			return
+		case "instruments":
+			runInstruments(os.Args[2:])
+			return
+		case "actions":
+			runActions(os.Args[2:])
+			return
+		case "calendar":
+			runCalendar(os.Args[2:])
+			return
+		}
 	}
-	defer resp.Body.Close()
+	runImport(os.Args[1:])
+}
 
-	var result struct {
-		Data []RawStockRow `json:"data"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+// runImport pulls a single date range from the scraper service
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	start := fs.String("start", time.Now().AddDate(0, 0, -7).Format("2006-01-02"), "first date to import (YYYY-MM-DD)")
+	end := fs.String("end", time.Now().Format("2006-01-02"), "last date to import (YYYY-MM-DD)")
+	api := fs.String("api", apiURL, "base URL of the bd-stock-api service")
+	var load loadOptions
+	load.register(fs)
+	fs.Parse(args)
+
+	raws, err := fetchHistorical(*api, *start, *end)
+	if err != nil {
 		panic(err)
 	}
-	fmt.Println("Rows received:", len(result.Data))
+	fmt.Println("Rows received:", len(raws))
 
-	db, err := sql.Open("postgres", "user=stock_cast password=password dbname=stock_cast sslmode=disable")
+	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		panic(err)
 	}
 	defer db.Close()
 
-	stmt, err := db.Prepare(`INSERT INTO stock_history (date, trading_code, ltp, high, low, openp, closep, ycp, trade, value, volume)
-        VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)`)
+	summary, err := loadRows(db, "api "+*start+".."+*end, raws, load)
 	if err != nil {
 		panic(err)
 	}
-	defer stmt.Close()
+	fmt.Println("Data import complete.", summary)
 
-	for _, raw := range result.Data {
-		row, err := convert(raw)
-		if err != nil {
-			fmt.Println("Skipping row due to error:", err)
-			continue
-		}
-		_, err = stmt.Exec(row.Date, row.TradingCode, row.Ltp, row.High, row.Low, row.Openp, row.Closep, row.Ycp, row.Trade, row.Value, row.Volume)
-		if err != nil {
-			fmt.Println("DB insert error:", err)
-		}
+	if err := refreshMarketSummaries(db); err != nil {
+		fmt.Println("Market summary refresh error:", err)
 	}
-	fmt.Println("Data import complete.")
 }
-
-// func main() {
-// 	db, err := sql.Open("postgres", "user=stock_cast password=password dbname=stock_cast sslmode=disable")
-//     if err != nil {
-//         panic(err)
-//     }
-//     defer db.Close()
-
-//     stmt, err := db.Prepare(`INSERT INTO stock_history (date, trading_code, ltp, high, low, openp, closep, ycp, trade, value, volume)
-//         VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)`)
-//     if err != nil {
-//         panic(err)
-//     }
-//     defer stmt.Close()
-
-//     for month := 1; month <= 12; month++ {
-//         start := fmt.Sprintf("2022-%02d-01", month)
-//         endTime := time.Date(2022, time.Month(month+1), 0, 0, 0, 0, 0, time.UTC)
-//         end := endTime.Format("2006-01-02")
-//         url := fmt.Sprintf("http://localhost:3000/v1/dse/historical?start=%s&end=%s", start, end)
-//         fmt.Println("Fetching:", url)
-
-//         resp, err := http.Get(url)
-//         if err != nil {
-//             fmt.Println("HTTP error:", err)
-//             continue
-//         }
-//         defer resp.Body.Close()
-
-//         var result struct {
-//             Data []RawStockRow `json:"data"`
-//         }
-//         if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-//             fmt.Println("JSON decode error:", err)
-//             continue
-//         }
-//         fmt.Printf("Rows received for %s: %d\n", start[:7], len(result.Data))
-
-//         for _, raw := range result.Data {
-//             row, err := convert(raw)
-//             if err != nil {
-//                 fmt.Println("Skipping row due to error:", err)
-//                 continue
-//             }
-//             _, err = stmt.Exec( row.Date, row.TradingCode, row.Ltp, row.High, row.Low, row.Openp, row.Closep, row.Ycp, row.Trade, row.Value, row.Volume)
-//             if err != nil {
-//                 fmt.Println("DB insert error:", err)
-//             }
-//         }
-//         fmt.Printf("Month %s import complete.\n", start[:7])
-//     }
-//     fmt.Println("All months import complete.")
-// }
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	reasonParse         = "parse_error"
+	reasonNegativePrice = "negative_price"
+	reasonNegativeCount = "negative_count"
+	reasonHighBelowLow  = "high_below_low"
+	reasonCloseOutside  = "close_outside_range"
+	reasonZeroVolume    = "zero_volume_with_value"
+)
+
+const insertRejectQuery = `INSERT INTO ingest_rejects (source, trading_code, date, reason, detail, payload)
+        VALUES ($1,$2,$3,$4,$5,$6)`
+
+// rejectError marks a row that must not be imported, reason groups rejects in the run summary
+type rejectError struct {
+	reason string
+	detail string
+}
+
+func (e *rejectError) Error() string {
+	return e.reason + ": " + e.detail
+}
+
+func reject(reason, format string, args ...any) *rejectError {
+	return &rejectError{reason: reason, detail: fmt.Sprintf(format, args...)}
+}
+
+// parser parses numeric fields and keeps the first error instead of silently defaulting to 0
+type parser struct {
+	err error
+}
+
+func (p *parser) float(field, s string) float64 {
+	f, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(s), ",", ""), 64)
+	if err != nil && p.err == nil {
+		p.err = reject(reasonParse, "%s: invalid number %q", field, s)
+	}
+	return f
+}
+
+func (p *parser) int(field, s string) int {
+	i, err := strconv.Atoi(strings.ReplaceAll(strings.TrimSpace(s), ",", ""))
+	if err != nil && p.err == nil {
+		p.err = reject(reasonParse, "%s: invalid integer %q", field, s)
+	}
+	return i
+}
+
+// validateRow rejects rows that cannot be real prices. The high/low checks only apply to rows that traded,
+// DSE reports 0 for the day range of codes without any trade.
+func validateRow(row StockRow) error {
+	prices := map[string]float64{
+		"ltp": row.Ltp, "high": row.High, "low": row.Low, "openp": row.Openp,
+		"closep": row.Closep, "ycp": row.Ycp,
+	}
+	for _, field := range []string{"ltp", "high", "low", "openp", "closep", "ycp"} {
+		if prices[field] < 0 {
+			return reject(reasonNegativePrice, "%s is %v", field, prices[field])
+		}
+	}
+	if row.Trade < 0 || row.Volume < 0 || row.Value < 0 {
+		return reject(reasonNegativeCount, "trade, value and volume must not be negative")
+	}
+	if row.Volume == 0 && row.Value != 0 {
+		return reject(reasonZeroVolume, "volume is 0 but value is %v", row.Value)
+	}
+	if row.Volume == 0 {
+		return nil
+	}
+	if row.High < row.Low {
+		return reject(reasonHighBelowLow, "high %v is below low %v", row.High, row.Low)
+	}
+	if row.Closep < row.Low || row.Closep > row.High {
+		return reject(reasonCloseOutside, "closep %v is outside [%v, %v]", row.Closep, row.Low, row.High)
+	}
+	return nil
+}
+
+func saveReject(stmt *sql.Stmt, source string, raw RawStockRow, rejectErr *rejectError) error {
+	payload, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+	_, err = stmt.Exec(source, raw.TradingCode, raw.Date, rejectErr.reason, rejectErr.detail, payload)
+	return err
+}